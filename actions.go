@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// writeActionsOutputs appends the counts of the run and the url of its
+// comment to the file github actions reads the outputs of a step from, so
+// that later steps can use them without parsing the reports again. Nothing
+// is written outside of github actions.
+func writeActionsOutputs(summary junit.Summary, commentUrl string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "total=%d\nfailed=%d\nskipped=%d\npass_rate=%.1f\ncomment_url=%s\n", summary.Tests, summary.Failures+summary.Errors, summary.Skipped, summary.PassRate(), commentUrl)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
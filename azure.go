@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// azureThreadMarker begins the thread that is edited on each run when no
+// --comment-key is given
+const azureThreadMarker = "<!-- xunit-to-github -->"
+
+type azureThreads struct {
+	Value []struct {
+		Id       int `json:"id"`
+		Comments []struct {
+			Id      int    `json:"id"`
+			Content string `json:"content"`
+		} `json:"comments"`
+	} `json:"value"`
+}
+
+func azureCredentials() string {
+	if token := os.Getenv("AZURE_DEVOPS_TOKEN"); token != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(":"+token))
+	}
+
+	if token := os.Getenv("SYSTEM_ACCESSTOKEN"); token != "" {
+		return "Bearer " + token
+	}
+
+	return ""
+}
+
+func postAzureThread(ctx context.Context, collectionUrl string, repositorySlug string, pullRequestId int, credentials string, marker string, body string) error {
+	parts := strings.SplitN(repositorySlug, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid azure devops repository slug, expected project/repository: %s", repositorySlug)
+	}
+
+	threadsUrl := fmt.Sprintf("%s/%s/_apis/git/repositories/%s/pullRequests/%d/threads", strings.TrimSuffix(collectionUrl, "/"), url.PathEscape(parts[0]), url.PathEscape(parts[1]), pullRequestId)
+	headers := map[string]string{
+		"Authorization": credentials,
+	}
+
+	if marker == "" {
+		marker = azureThreadMarker
+	}
+	body = marker + "\n" + body
+
+	responseBody, err := sendJSON(ctx, "GET", threadsUrl+"?api-version=7.0", headers, nil, 200)
+	if err != nil {
+		return err
+	}
+
+	var threads azureThreads
+	if err := json.Unmarshal(responseBody, &threads); err != nil {
+		return err
+	}
+
+	for _, thread := range threads.Value {
+		if len(thread.Comments) == 0 || !strings.HasPrefix(thread.Comments[0].Content, marker) {
+			continue
+		}
+
+		commentUrl := fmt.Sprintf("%s/%d/comments/%d?api-version=7.0", threadsUrl, thread.Id, thread.Comments[0].Id)
+		message := map[string]interface{}{
+			"content": body,
+		}
+
+		_, err := sendJSON(ctx, "PATCH", commentUrl, headers, message, 200)
+		return err
+	}
+
+	thread := map[string]interface{}{
+		"comments": []map[string]interface{}{
+			{"parentCommentId": 0, "content": body, "commentType": 1},
+		},
+		"status": 1,
+	}
+
+	_, err = sendJSON(ctx, "POST", threadsUrl+"?api-version=7.0", headers, thread, 200)
+	return err
+}
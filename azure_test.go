@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostAzureThread(t *testing.T) {
+	threads := `{"value": [
+		{"id": 1, "comments": [{"id": 10, "content": "<!-- xunit-to-github -->\nall results"}]},
+		{"id": 2, "comments": [{"id": 20, "content": "<!-- xunit-to-github-comment unit -->\nunit results"}]}
+	]}`
+	tests := []struct {
+		name    string
+		marker  string
+		request string
+	}{
+		{"edits the default thread", "", "PATCH /p/_apis/git/repositories/r/pullRequests/7/threads/1/comments/10?api-version=7.0"},
+		{"edits the thread of the key", commentMarker("unit"), "PATCH /p/_apis/git/repositories/r/pullRequests/7/threads/2/comments/20?api-version=7.0"},
+		{"creates a thread for a new key", commentMarker("e2e"), "POST /p/_apis/git/repositories/r/pullRequests/7/threads?api-version=7.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.RequestURI())
+				fmt.Fprint(w, threads)
+			}))
+			defer server.Close()
+
+			if err := postAzureThread(context.Background(), server.URL, "p/r", 7, "Bearer t", test.marker, "results"); err != nil {
+				t.Fatalf("postAzureThread() error = %s", err)
+			}
+			if len(requests) != 2 || requests[1] != test.request {
+				t.Errorf("requests = %v, want %q after listing the threads", requests, test.request)
+			}
+		})
+	}
+}
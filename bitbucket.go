@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func bitbucketCredentials() string {
+	if token := os.Getenv("BITBUCKET_ACCESS_TOKEN"); token != "" {
+		return "Bearer " + token
+	}
+
+	username := os.Getenv("BITBUCKET_USERNAME")
+	password := os.Getenv("BITBUCKET_APP_PASSWORD")
+	if username == "" || password == "" {
+		return ""
+	}
+
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func postBitbucketComment(ctx context.Context, repositorySlug string, pullRequestId int, credentials string, body string) error {
+	url := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/pullrequests/%d/comments", repositorySlug, pullRequestId)
+	headers := map[string]string{
+		"Authorization": credentials,
+	}
+
+	message := map[string]interface{}{
+		"content": map[string]string{
+			"raw": body,
+		},
+	}
+
+	_, err := sendJSON(ctx, "POST", url, headers, message, 201)
+	return err
+}
+
+func postBitbucketReport(ctx context.Context, repositorySlug string, commit string, credentials string, title string, summary junit.Summary, passed bool, jobUrl string) error {
+	url := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/commit/%s/reports/xunit-to-github", repositorySlug, commit)
+	headers := map[string]string{
+		"Authorization": credentials,
+	}
+
+	if title == "" {
+		title = "Test results"
+	}
+
+	result := "PASSED"
+	if !passed {
+		result = "FAILED"
+	}
+
+	report := map[string]interface{}{
+		"title":       title,
+		"details":     summary.String(),
+		"report_type": "TEST",
+		"reporter":    "xunit-to-github",
+		"result":      result,
+		"data": []map[string]interface{}{
+			{"title": "Tests", "type": "NUMBER", "value": summary.Tests},
+			{"title": "Failures", "type": "NUMBER", "value": summary.Failures},
+			{"title": "Errors", "type": "NUMBER", "value": summary.Errors},
+			{"title": "Skipped", "type": "NUMBER", "value": summary.Skipped},
+		},
+	}
+	if jobUrl != "" {
+		report["link"] = jobUrl
+	}
+
+	_, err := sendJSON(ctx, "PUT", url, headers, report, 200)
+	return err
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func postBuildkiteAnnotation(ctx context.Context, annotationContext string, passed bool, body string) error {
+	style := "success"
+	if !passed {
+		style = "error"
+	}
+
+	cmd := exec.CommandContext(ctx, "buildkite-agent", "annotate", "--style", style, "--context", annotationContext)
+	cmd.Stdin = strings.NewReader(body)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("buildkite-agent: %s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/github"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+// checkConclusions maps the outcome of a run to the conclusion of its check
+// runs, for the outcomes that are not the default
+type checkConclusions map[string]string
+
+var (
+	checkOutcomes           = []string{"passed", "failed", "flaky", "quarantined", "skipped"}
+	checkConclusionValues   = []string{"success", "failure", "neutral", "skipped", "action_required"}
+	defaultCheckConclusions = checkConclusions{"passed": "success", "failed": "failure", "flaky": "success", "quarantined": "success", "skipped": "success"}
+	annotationStatuses      = []string{"failed", "error", "flaky", "quarantined"}
+	annotationLevelValues   = []string{"failure", "warning", "notice", "none"}
+	defaultAnnotationLevels = annotationLevels{"failed": "failure", "error": "failure", "flaky": "none", "quarantined": "none"}
+)
+
+func (c *checkConclusions) String() string {
+	return mappingString(*c)
+}
+
+func (c *checkConclusions) Set(value string) error {
+	if *c == nil {
+		*c = checkConclusions{}
+	}
+	return setMapping(*c, value, checkOutcomes, checkConclusionValues)
+}
+
+// outcome describes the run as failed, or when it passed, as skipped when
+// every test was skipped, then quarantined or flaky when any tests were
+func outcome(summary junit.Summary, passed bool) string {
+	switch {
+	case !passed:
+		return "failed"
+	case summary.Tests == summary.Skipped:
+		return "skipped"
+	case summary.Quarantined > 0:
+		return "quarantined"
+	case summary.Flaky > 0:
+		return "flaky"
+	}
+	return "passed"
+}
+
+// conclusion is the conclusion of a check run for the results, which is
+// neutral when there are no results to judge
+func (c checkConclusions) conclusion(summary *junit.Summary, passed bool) string {
+	if summary == nil {
+		return "neutral"
+	}
+	key := outcome(*summary, passed)
+	if conclusion, ok := c[key]; ok {
+		return conclusion
+	}
+	return defaultCheckConclusions[key]
+}
+
+// annotationLevels maps the statuses of testcases to the level their failures
+// are annotated at, or none to leave them out, for the statuses that are not
+// the default
+type annotationLevels map[string]string
+
+func (l *annotationLevels) String() string {
+	return mappingString(*l)
+}
+
+func (l *annotationLevels) Set(value string) error {
+	if *l == nil {
+		*l = annotationLevels{}
+	}
+	return setMapping(*l, value, annotationStatuses, annotationLevelValues)
+}
+
+func (l annotationLevels) level(status junit.Status) string {
+	if level, ok := l[string(status)]; ok {
+		return level
+	}
+	if level, ok := defaultAnnotationLevels[string(status)]; ok {
+		return level
+	}
+	return "none"
+}
+
+// setMapping sets the key=value pairs of a comma separated list in the
+// mapping, checking that each key and value is one of those allowed
+func setMapping(mapping map[string]string, value string, keys []string, values []string) error {
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid mapping %s, expected key=value", pair)
+		}
+		key, mapped := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !contains(keys, key) {
+			return fmt.Errorf("unknown %s, expected one of %s", key, strings.Join(keys, ", "))
+		}
+		if !contains(values, mapped) {
+			return fmt.Errorf("unknown %s, expected one of %s", mapped, strings.Join(values, ", "))
+		}
+		mapping[key] = mapped
+	}
+	return nil
+}
+
+func mappingString(mapping map[string]string) string {
+	var pairs []string
+	for key, value := range mapping {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// publishCheckRuns publishes a body too long for a comment as check runs on
+// the head of the pull request, each holding two chunks of it as its summary
+// and text, and returns a short comment body linking to them, so that nothing
+// is cut off
+func publishCheckRuns(ctx context.Context, client *github.Client, options *commentOptions, summary *junit.Summary, passed bool, body string) (string, error) {
+	pullRequest, err := client.PullRequest(ctx, options.RepositorySlug, options.PullRequestId)
+	if err != nil {
+		return "", err
+	}
+
+	name := options.Title
+	if name == "" {
+		name = "Test results"
+	}
+	title := name
+	if summary != nil {
+		title = summary.String()
+	}
+
+	chunks := splitBody(body, github.MaxCheckRunOutput)
+	parts := (len(chunks) + 1) / 2
+	var links []string
+	for part := 0; part < parts; part++ {
+		output := github.CheckRunOutput{Title: title, Summary: chunks[part*2]}
+		if part*2+1 < len(chunks) {
+			output.Text = chunks[part*2+1]
+		}
+
+		partName := name
+		if parts > 1 {
+			partName = fmt.Sprintf("%s (%d of %d)", name, part+1, parts)
+		}
+		checkRun, err := client.CreateCheckRun(ctx, options.RepositorySlug, partName, pullRequest.Head.Sha, options.conclusions.conclusion(summary, passed), output)
+		if err != nil {
+			return "", err
+		}
+		links = append(links, fmt.Sprintf("[%s](%s)", partName, checkRun.HtmlUrl))
+	}
+	logger.Info("results published as check runs", "count", parts, "length", len(body))
+
+	comment := ""
+	if summary != nil {
+		comment += summary.String() + "\n\n"
+	}
+	comment += fmt.Sprintf("> 📋 the results are too long for a comment, so they were published in full as %s\n", strings.Join(links, ", "))
+	return render.Decorate(comment, options.Title, options.JobUrl), nil
+}
+
+// splitBody splits the body into chunks of at most limit bytes, breaking
+// between lines where it can and never within a character
+func splitBody(body string, limit int) []string {
+	var chunks []string
+	for len(body) > limit {
+		end := strings.LastIndex(body[:limit], "\n") + 1
+		if end == 0 {
+			end = limit
+			for end > 0 && !utf8.RuneStart(body[end]) {
+				end--
+			}
+		}
+		chunks = append(chunks, body[:end])
+		body = body[end:]
+	}
+	return append(chunks, body)
+}
+
+// annotationMessageLength is the most of a failure message included in its
+// annotation, which github shows inline in the diff
+const annotationMessageLength = 4096
+
+// failureAnnotations returns an annotation for each failure located within
+// the repository, at the level for the status of its testcase
+func failureAnnotations(results junit.Report, levels annotationLevels) []github.Annotation {
+	var annotations []github.Annotation
+	for _, suite := range results.Suites {
+		for _, testcase := range suite.Cases {
+			level := levels.level(testcase.Status)
+			if level == "none" || testcase.Location == nil {
+				continue
+			}
+			message := strings.TrimSpace(testcase.Failure.Message)
+			if message == "" {
+				message = string(testcase.Status)
+			}
+			// failures located by their classname alone are annotated at the
+			// top of the file
+			line := testcase.Location.Line
+			if line == 0 {
+				line = 1
+			}
+			annotations = append(annotations, github.Annotation{
+				Path:      testcase.Location.File,
+				StartLine: line,
+				EndLine:   line,
+				Level:     level,
+				Title:     testcase.DisplayId(),
+				Message:   splitBody(message, annotationMessageLength)[0],
+			})
+		}
+	}
+	return annotations
+}
+
+// changedFileAnnotations splits the annotations into those in the files the
+// pull request changes and the rest, so that the diff only shows failures its
+// author can fix. Outside of a pull request, or when its files cannot be
+// fetched, every annotation is kept.
+func changedFileAnnotations(ctx context.Context, client *github.Client, options *commentOptions, annotations []github.Annotation) ([]github.Annotation, []github.Annotation) {
+	if options.PullRequestId == 0 || len(annotations) == 0 {
+		return annotations, nil
+	}
+	files, err := client.PullRequestFiles(ctx, options.RepositorySlug, options.PullRequestId)
+	if err != nil {
+		logger.Warn("could not fetch the files changed by the pull request, annotating every failure", "error", err)
+		return annotations, nil
+	}
+
+	changed := map[string]bool{}
+	for _, file := range files {
+		if file.Status != "removed" {
+			changed[file.Filename] = true
+		}
+	}
+	var inside, outside []github.Annotation
+	for _, annotation := range annotations {
+		if changed[annotation.Path] {
+			inside = append(inside, annotation)
+		} else {
+			outside = append(outside, annotation)
+		}
+	}
+	return inside, outside
+}
+
+// publishCheckRun creates a check run for the results on the head of the pull
+// request, or the commit outside of one, with an annotation for each located
+// failure. Annotations are sent in batches of as many as github accepts per
+// request, up to --max-annotations in all. Batches that github rejects, such
+// as for a path that is not in the commit, are warned about and counted in
+// the summary rather than failing the run, since the check run itself is
+// still created.
+func publishCheckRun(ctx context.Context, options *reportOptions, results junit.Report, body string) error {
+	accessToken, _, err := githubAccessToken(ctx, options.Comment)
+	if err != nil {
+		return err
+	}
+	if accessToken == "" || options.Comment.RepositorySlug == "" {
+		return fmt.Errorf("a github token and --repository-slug are required to create check runs")
+	}
+	client := &github.Client{HTTPClient: httpClient, BaseURL: os.Getenv("GITHUB_API_URL"), AccessToken: accessToken}
+
+	headSha := options.Commit
+	if options.Comment.PullRequestId != 0 {
+		pullRequest, err := client.PullRequest(ctx, options.Comment.RepositorySlug, options.Comment.PullRequestId)
+		if err != nil {
+			return err
+		}
+		if label := skipLabel(pullRequest, options.Comment.SkipLabels); label != "" {
+			logger.Info("check run skipped", "label", label)
+			return nil
+		}
+		headSha = pullRequest.Head.Sha
+	}
+	if headSha == "" {
+		return fmt.Errorf("--commit is required to create check runs outside of a pull request")
+	}
+
+	annotations := failureAnnotations(results, options.AnnotationLevels)
+	var outside []github.Annotation
+	if options.ChangedFilesOnly {
+		annotations, outside = changedFileAnnotations(ctx, client, options.Comment, annotations)
+	}
+	omitted := 0
+	if options.MaxAnnotations >= 0 && len(annotations) > options.MaxAnnotations {
+		omitted = len(annotations) - options.MaxAnnotations
+		annotations = annotations[:options.MaxAnnotations]
+	}
+
+	summary := func(rejected int) string {
+		text := results.Summary.String() + "\n"
+		if omitted > 0 {
+			text += fmt.Sprintf("\n> %d more failures are not annotated\n", omitted)
+		}
+		if len(outside) > 0 {
+			text += fmt.Sprintf("\n> %d failures outside of the files changed by the pull request are not annotated:\n\n", len(outside))
+			for _, annotation := range outside {
+				text += fmt.Sprintf("- `%s` at `%s:%d`\n", annotation.Title, annotation.Path, annotation.StartLine)
+			}
+		}
+		if rejected > 0 {
+			text += fmt.Sprintf("\n> ⚠️ %d annotations could not be added\n", rejected)
+		}
+		return splitBody(text, github.MaxCheckRunOutput)[0]
+	}
+	output := github.CheckRunOutput{Title: results.Summary.String(), Summary: summary(0), Text: splitBody(body, github.MaxCheckRunOutput)[0]}
+
+	batch := func(start int) []github.Annotation {
+		end := start + github.MaxAnnotationsPerRequest
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+		return annotations[start:end]
+	}
+	conclusion := options.CheckConclusions.conclusion(&results.Summary, options.Thresholds.Passed(results.Summary))
+	output.Annotations = batch(0)
+	rejected := 0
+	checkRun, err := client.CreateCheckRun(ctx, options.Comment.RepositorySlug, options.CheckRun, headSha, conclusion, output)
+	var apiError *github.Error
+	if err != nil && len(output.Annotations) > 0 && errors.As(err, &apiError) && apiError.StatusCode == 422 {
+		logger.Warn("could not annotate check run", "annotations", len(output.Annotations), "error", err)
+		rejected += len(output.Annotations)
+		output.Annotations = nil
+		checkRun, err = client.CreateCheckRun(ctx, options.Comment.RepositorySlug, options.CheckRun, headSha, conclusion, output)
+	}
+	if err != nil {
+		return err
+	}
+
+	for start := github.MaxAnnotationsPerRequest; start < len(annotations); start += github.MaxAnnotationsPerRequest {
+		update := github.CheckRunOutput{Title: output.Title, Summary: output.Summary, Annotations: batch(start)}
+		if _, err := client.UpdateCheckRun(ctx, options.Comment.RepositorySlug, checkRun.Id, update); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			logger.Warn("could not annotate check run", "annotations", len(update.Annotations), "error", err)
+			rejected += len(update.Annotations)
+		}
+	}
+	if rejected > 0 {
+		update := github.CheckRunOutput{Title: output.Title, Summary: summary(rejected)}
+		if _, err := client.UpdateCheckRun(ctx, options.Comment.RepositorySlug, checkRun.Id, update); err != nil {
+			logger.Warn("could not update check run summary", "error", err)
+		}
+	}
+
+	logger.Info("results published", "publisher", "checks", "annotations", len(annotations)-rejected, "url", checkRun.HtmlUrl)
+	return nil
+}
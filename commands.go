@@ -0,0 +1,847 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+// readInput reads the named file, or stdin when the name is empty or "-"
+func readInput(args []string) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(args[0])
+}
+
+// readCoverage reads and combines the coverage reports in the files
+func readCoverage(files []string, root string) (junit.Coverage, error) {
+	var coverages []junit.Coverage
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return junit.Coverage{}, err
+		}
+		coverage, err := junit.ParseCoverage(data, root)
+		if err != nil {
+			return junit.Coverage{}, fmt.Errorf("%s: %w", file, err)
+		}
+		coverages = append(coverages, coverage)
+	}
+	return junit.CombineCoverage(coverages...), nil
+}
+
+// readBenchmarks reads the benchmark results in the files
+func readBenchmarks(files []string) ([]junit.Benchmark, error) {
+	var benchmarks []junit.Benchmark
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := junit.ParseBenchmarks(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		benchmarks = append(benchmarks, parsed...)
+	}
+	return benchmarks, nil
+}
+
+// readResults reads json results from the files, or stdin when there are
+// none, combining the results of several files such as those of each build
+// in a matrix
+func readResults(args []string, recount bool) (junit.Report, error) {
+	if len(args) <= 1 {
+		data, err := readInput(args)
+		if err != nil {
+			return junit.Report{}, err
+		}
+		return decodeResults(data, recount)
+	}
+
+	var reports []junit.Report
+	for _, arg := range args {
+		data, err := readInput([]string{arg})
+		if err != nil {
+			return junit.Report{}, err
+		}
+		results, err := decodeResults(data, recount)
+		if err != nil {
+			return junit.Report{}, fmt.Errorf("%s: %w", arg, err)
+		}
+		reports = append(reports, results)
+	}
+	return junit.Combine(reports...), nil
+}
+
+// decodeResults decodes json results from the parse command. When recount is
+// set, the counts are recomputed from the testcases, so that results edited
+// between steps, such as with jq, are summarized correctly.
+func decodeResults(data []byte, recount bool) (junit.Report, error) {
+	var results junit.Report
+	if err := json.Unmarshal(data, &results); err != nil {
+		return results, err
+	}
+	if recount {
+		results.Recount()
+	}
+	return results, nil
+}
+
+type command struct {
+	Name        string
+	Description string
+	Flags       func() *flag.FlagSet
+	Run         func(args []string)
+}
+
+// commands lists the subcommands, with the default report command first
+var commands []command
+
+func init() {
+	commands = []command{
+		{"", "Parse, render, and publish reports", func() *flag.FlagSet { flags, _ := newReportFlags(); return flags }, runReport},
+		{"parse", "Convert xml reports into json results", func() *flag.FlagSet { flags, _ := newParseFlags(); return flags }, runParse},
+		{"render", "Convert json results into markdown or another output format", func() *flag.FlagSet { flags, _ := newRenderFlags(); return flags }, runRender},
+		{"publish", "Post markdown as a comment", func() *flag.FlagSet { flags, _ := newPublishFlags(); return flags }, runPublish},
+		{"post", "Post a comment exported with --export", func() *flag.FlagSet { flags, _ := newPostFlags(); return flags }, runPost},
+		{"merge", "Combine xml reports into a single report", func() *flag.FlagSet { flags, _ := newMergeFlags(); return flags }, runMerge},
+		{"compare", "Diff the xml reports of two runs", func() *flag.FlagSet { flags, _ := newCompareFlags(); return flags }, runCompare},
+		{"view", "Browse xml reports in an interactive terminal ui", func() *flag.FlagSet { flags, _ := newViewFlags(); return flags }, runView},
+		{"preview", "Show the comment for xml reports as it would roughly appear on github", func() *flag.FlagSet { flags, _ := newPreviewFlags(); return flags }, runPreview},
+		{"doctor", "Check that comments can be posted before ci relies on it", func() *flag.FlagSet { flags, _ := newDoctorFlags(); return flags }, runDoctor},
+		{"completion", "Print a bash, zsh, or fish completion script", func() *flag.FlagSet { return flag.NewFlagSet("xunit-to-github completion", flag.ExitOnError) }, runCompletion},
+		{"version", "Print the version", func() *flag.FlagSet { return flag.NewFlagSet("xunit-to-github version", flag.ExitOnError) }, func([]string) { fmt.Println(versionString()) }},
+	}
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+type parseOptions struct {
+	Filter  *filterOptions
+	Parsing *parsingOptions
+}
+
+func newParseFlags() (*flag.FlagSet, *parseOptions) {
+	flags := flag.NewFlagSet("xunit-to-github parse", flag.ExitOnError)
+	options := &parseOptions{}
+	options.Filter = addFilterFlags(flags)
+	options.Parsing = addParsingFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runParse converts xml reports into json results on stdout
+func runParse(args []string) {
+	flags, options := newParseFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	filter, err := options.Filter.compile()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid filter", "error", err)
+	}
+
+	files, missing, err := options.Parsing.findFiles(flags.Args())
+	if err != nil {
+		logger.Fatal(exitParseError, "could not find reports", "error", err)
+	}
+
+	results, err := options.Parsing.parseFiles(ctx, files)
+	if err != nil {
+		logger.Fatal(exitParseError, "could not parse reports", "error", err)
+	}
+	results.MissingPaths = missing
+
+	if err := writeJSON(os.Stdout, junit.FilterReport(junit.MarkFlaky(results), filter)); err != nil {
+		logger.Fatal(exitPublishError, "could not write results", "error", err)
+	}
+}
+
+type renderOptions struct {
+	SkipOk         bool
+	Title          string
+	JobUrl         string
+	Output         string
+	TemplateFile   string
+	Time           *timeOptions
+	ShowAssertions bool
+	Tree           bool
+	Recount        bool
+}
+
+func newRenderFlags() (*flag.FlagSet, *renderOptions) {
+	flags := flag.NewFlagSet("xunit-to-github render", flag.ExitOnError)
+	options := &renderOptions{}
+	flags.BoolVar(&options.SkipOk, "skip-ok", false, "skip-ok: Whether to skip ok tests or not")
+	flags.BoolVar(&options.Tree, "tree", false, "tree: Whether to lay out tests in a tree of collapsible sections built from their classnames, rather than a flat list")
+	flags.StringVar(&options.Title, "title", "", "title: A title for the comment")
+	flags.StringVar(&options.JobUrl, "job-url", "", "job-url: A url for the report")
+	addOutputFlags(flags, &options.Output, &options.TemplateFile, "markdown")
+	options.Time = addTimeFlags(flags)
+	flags.BoolVar(&options.ShowAssertions, "show-assertions", false, "show-assertions: Whether to include how many assertions the tests checked, when the reports count them")
+	flags.BoolVar(&options.Recount, "recount", false, "recount: Whether to recompute counts from the testcases, for results edited since they were parsed")
+	addCommonFlags(flags)
+	return flags, options
+}
+
+func addOutputFlags(flags *flag.FlagSet, output *string, templateFile *string, defaultOutput string) {
+	flags.StringVar(output, "output", defaultOutput, "output: The format to render results in ("+strings.Join(render.Formats(), ", ")+")")
+	flags.StringVar(templateFile, "template", "", "template: A go text/template file to render results with when the output is template")
+}
+
+type timeOptions struct {
+	Timezone string
+	Format   string
+}
+
+func addTimeFlags(flags *flag.FlagSet) *timeOptions {
+	options := &timeOptions{}
+	flags.StringVar(&options.Timezone, "timezone", "UTC", "timezone: The timezone to show when the run started in, such as America/New_York or Local")
+	flags.StringVar(&options.Format, "time-format", render.DefaultTimeFormat, "time-format: The go time layout to show when the run started in")
+	return options
+}
+
+// location loads the timezone, which is utc when unset
+func (o *timeOptions) location() (*time.Location, error) {
+	return time.LoadLocation(o.Timezone)
+}
+
+// newRenderer returns the renderer for the output format, reading the
+// template file when one is set
+func newRenderer(output string, templateFile string, options render.Options) (render.Renderer, error) {
+	if templateFile != "" {
+		data, err := ioutil.ReadFile(templateFile)
+		if err != nil {
+			return nil, err
+		}
+		options.Template = string(data)
+	}
+	return render.New(output, options)
+}
+
+// runRender converts json results from files or stdin into the output format
+// on stdout
+func runRender(args []string) {
+	flags, options := newRenderFlags()
+	parseFlags(flags, args, false)
+
+	location, err := options.Time.location()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid timezone", "error", err)
+	}
+
+	renderer, err := newRenderer(options.Output, options.TemplateFile, render.Options{SkipOk: options.SkipOk, Title: options.Title, JobUrl: options.JobUrl, Location: location, TimeFormat: options.Time.Format, Assertions: options.ShowAssertions, Tree: options.Tree})
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid output", "error", err)
+	}
+
+	results, err := readResults(flags.Args(), options.Recount)
+	if err != nil {
+		logger.Fatal(exitParseError, "could not read results", "error", err)
+	}
+
+	if err := renderer.Render(os.Stdout, results); err != nil {
+		logger.Fatal(exitConfigError, "could not render results", "error", err)
+	}
+}
+
+type publishOptions struct {
+	Comment     *commentOptions
+	Input       string
+	SkipOk      bool
+	Tree        bool
+	Recount     bool
+	ResultsFile string
+	Export      string
+	Thresholds  *Thresholds
+}
+
+func newPublishFlags() (*flag.FlagSet, *publishOptions) {
+	flags := flag.NewFlagSet("xunit-to-github publish", flag.ExitOnError)
+	options := &publishOptions{}
+	options.Comment = addCommentFlags(flags)
+	flags.StringVar(&options.Input, "input", "markdown", "input: The format of the input (markdown, or json results from the parse command)")
+	flags.BoolVar(&options.SkipOk, "skip-ok", false, "skip-ok: Whether to skip ok tests or not, when the input is json")
+	flags.BoolVar(&options.Tree, "tree", false, "tree: Whether to lay out tests in a tree of collapsible sections built from their classnames, when the input is json")
+	flags.BoolVar(&options.Recount, "recount", false, "recount: Whether to recompute counts from the testcases, for json results edited since they were parsed")
+	flags.StringVar(&options.ResultsFile, "results", "", "results: A json results file from the parse command, used for label votes and reports")
+	flags.StringVar(&options.Export, "export", "", "export: A file to write the prepared comment to rather than posting it, for the post command to send later")
+	options.Thresholds = addThresholdFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runPublish posts markdown, or the comment for json results, from files or
+// stdin
+func runPublish(args []string) {
+	flags, options := newPublishFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	if options.Input != "markdown" && options.Input != "json" {
+		logger.Fatal(exitConfigError, "invalid input", "error", fmt.Errorf("unknown input: %s", options.Input))
+	}
+
+	var body string
+	var summary *junit.Summary
+	passed := true
+	if options.Input == "json" {
+		results, err := readResults(flags.Args(), options.Recount)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not read results", "error", err)
+		}
+		body = render.Markdown{SkipOk: options.SkipOk, Tree: options.Tree}.Body(results, ioutil.Discard)
+		if body == "" {
+			return
+		}
+		body = render.Decorate(body, options.Comment.Title, options.Comment.JobUrl)
+		summary = &results.Summary
+		passed = options.Thresholds.Passed(results.Summary)
+	} else {
+		input, err := readInput(flags.Args())
+		if err != nil {
+			logger.Fatal(exitParseError, "could not read comment body", "error", err)
+		}
+		if len(input) == 0 {
+			return
+		}
+		body = string(input)
+	}
+	if options.ResultsFile != "" {
+		data, err := ioutil.ReadFile(options.ResultsFile)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not read results", "error", err)
+		}
+
+		results, err := decodeResults(data, options.Recount)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not decode results", "error", err)
+		}
+		summary = &results.Summary
+		passed = options.Thresholds.Passed(results.Summary)
+	}
+
+	if options.Export != "" {
+		if err := exportComment(options.Export, options.Comment, summary, passed, body); err != nil {
+			logger.Fatal(exitPublishError, "could not export comment", "error", err)
+		}
+		return
+	}
+
+	if _, err := postComment(ctx, options.Comment, summary, passed, body); err != nil {
+		logger.Fatal(exitPublishError, "could not post comment", "error", err)
+	}
+}
+
+type reportOptions struct {
+	Version             bool
+	SkipOk              bool
+	Quiet               bool
+	Comment             *commentOptions
+	SlackWebhookUrl     string
+	SlackOnlyOnFailure  bool
+	TeamsWebhookUrl     string
+	DiscordWebhookUrl   string
+	WebhookUrl          string
+	WebhookSecret       string
+	WebhookHeaders      stringSlice
+	SmtpHost            string
+	SmtpUsername        string
+	EmailFrom           string
+	EmailOnlyOnFailure  bool
+	EmailTo             stringSlice
+	UploadUrl           string
+	UploadEndpoint      string
+	UploadPresignExpiry time.Duration
+	PushgatewayUrl      string
+	PushgatewayJob      string
+	Branch              string
+	Commit              string
+	Datadog             bool
+	DatadogSite         string
+	OtlpEndpoint        string
+	HistoryDb           string
+	HistoryWindow       int
+	Baseline            stringSlice
+	BaselineFactor      float64
+	BaselineMinimum     time.Duration
+	Quarantine          string
+	Owners              string
+	MentionOwners       bool
+	Reproduce           bool
+	ReproduceTemplates  reproduceTemplates
+	EmbedMetadata       bool
+	RunId               string
+	ComparePrevious     bool
+	ElasticsearchUrl    string
+	ElasticsearchIndex  string
+	Teamcity            bool
+	FailOnFailure       bool
+	Thresholds          *Thresholds
+	Filter              *filterOptions
+	Watch               bool
+	WatchInterval       time.Duration
+	WatchComment        bool
+	Parsing             *parsingOptions
+	Publish             stringSlice
+	Output              string
+	TemplateFile        string
+	Time                *timeOptions
+	ShowAssertions      bool
+	Tree                bool
+	SummaryOnly         bool
+	CommentPerSuite     bool
+	EmptyReport         string
+	RequireSuites       stringSlice
+	Export              string
+	Deadline            time.Duration
+	CheckRun            string
+	MaxAnnotations      int
+	AnnotationLevels    annotationLevels
+	CheckConclusions    checkConclusions
+	ChangedFilesOnly    bool
+	Coverage            stringSlice
+	CoverageBaseline    stringSlice
+	Benchmarks          stringSlice
+	BenchmarkBaseline   stringSlice
+	BenchmarkThreshold  float64
+}
+
+// deadlinePublishShare reserves a share of the --deadline for publishing, so
+// that results parsed before it are still published rather than the run
+// timing out while parsing
+const deadlinePublishShare = 4
+
+func newReportFlags() (*flag.FlagSet, *reportOptions) {
+	flags := flag.NewFlagSet("xunit-to-github", flag.ExitOnError)
+	options := &reportOptions{}
+	flags.BoolVar(&options.Version, "version", false, "version: Print the version and exit")
+	flags.BoolVar(&options.SkipOk, "skip-ok", false, "skip-ok: Whether to skip ok tests or not")
+	flags.BoolVar(&options.Tree, "tree", false, "tree: Whether to lay out tests in a tree of collapsible sections built from their classnames, rather than a flat list")
+	flags.BoolVar(&options.SummaryOnly, "summary-only", false, "summary-only: Whether to only comment with the counts and pass rate of the run and a link to the full report, rather than every failure")
+	flags.BoolVar(&options.CommentPerSuite, "comment-per-suite", false, "comment-per-suite: Whether to post a comment for each suite, or each labeled build, rather than one for the whole run")
+	flags.BoolVar(&options.Quiet, "quiet", false, "quiet: Whether to only print a one-line summary and errors rather than every test")
+	options.Comment = addCommentFlags(flags)
+	flags.StringVar(&options.SlackWebhookUrl, "slack-webhook-url", "", "slack-webhook-url: A slack incoming webhook url to send a summary to")
+	flags.BoolVar(&options.SlackOnlyOnFailure, "slack-only-on-failure", false, "slack-only-on-failure: Whether to only notify slack when tests fail")
+	flags.StringVar(&options.TeamsWebhookUrl, "teams-webhook-url", "", "teams-webhook-url: A microsoft teams incoming webhook url to send a summary to")
+	flags.StringVar(&options.DiscordWebhookUrl, "discord-webhook-url", "", "discord-webhook-url: A discord webhook url to send a summary to")
+	flags.StringVar(&options.WebhookUrl, "webhook-url", "", "webhook-url: A url to post the json results to")
+	flags.StringVar(&options.WebhookSecret, "webhook-secret", "", "webhook-secret: A secret used to sign webhook payloads")
+	flags.Var(&options.WebhookHeaders, "webhook-header", "webhook-header: A header to send with webhook payloads, in the form 'Name: value'")
+	flags.StringVar(&options.SmtpHost, "smtp-host", "", "smtp-host: The host:port of an smtp server to send an email report through")
+	flags.StringVar(&options.SmtpUsername, "smtp-username", "", "smtp-username: The username to authenticate to the smtp server with")
+	flags.StringVar(&options.EmailFrom, "email-from", "xunit-to-github@localhost", "email-from: The address to send the email report from")
+	flags.BoolVar(&options.EmailOnlyOnFailure, "email-only-on-failure", false, "email-only-on-failure: Whether to only send an email report when tests fail")
+	flags.Var(&options.EmailTo, "email-to", "email-to: An address to send the email report to")
+	flags.StringVar(&options.UploadUrl, "upload-url", "", "upload-url: An s3:// or gs:// bucket and prefix to upload the html and json reports, and the attachments of failed tests, to")
+	flags.StringVar(&options.UploadEndpoint, "upload-endpoint", "", "upload-endpoint: A custom endpoint for s3-compatible object storage")
+	flags.DurationVar(&options.UploadPresignExpiry, "upload-presign-expiry", 0, "upload-presign-expiry: How long presigned report urls are valid for, up to 168h, linking to the object directly when unset")
+	flags.StringVar(&options.PushgatewayUrl, "pushgateway-url", "", "pushgateway-url: A prometheus pushgateway url to push test metrics to")
+	flags.StringVar(&options.PushgatewayJob, "pushgateway-job", "xunit-to-github", "pushgateway-job: The job name to push prometheus metrics under")
+	flags.StringVar(&options.Branch, "branch", "", "branch: The branch the tests were run against, detected from the ci environment when unset")
+	flags.StringVar(&options.Commit, "commit", "", "commit: The commit the tests were run against, detected from the ci environment when unset")
+	flags.StringVar(&options.RunId, "run-id", "", "run-id: The id of the ci run the tests ran in, detected from the ci environment when unset")
+	flags.BoolVar(&options.EmbedMetadata, "embed-metadata", true, "embed-metadata: Whether to embed the results as json in a hidden html comment in the comment, for later runs and other tools to read")
+	flags.BoolVar(&options.ComparePrevious, "compare-previous", true, "compare-previous: Whether to mark failures as new or still failing from the results embedded in the previous comment on the pull request")
+	flags.BoolVar(&options.Datadog, "datadog", false, "datadog: Whether to submit test metrics and events to datadog, with the api key in DD_API_KEY")
+	flags.StringVar(&options.DatadogSite, "datadog-site", getenvDefault("DD_SITE", "datadoghq.com"), "datadog-site: The datadog site to send metrics and events to")
+	flags.StringVar(&options.OtlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "otlp-endpoint: An opentelemetry otlp/http endpoint to export test spans to")
+	flags.StringVar(&options.HistoryDb, "history-db", "", "history-db: A sqlite database path, or rqlite url, to record the run in")
+	flags.IntVar(&options.HistoryWindow, "history-window", 20, "history-window: How many recent runs to count each failing test's failures over, when --history-db is set")
+	flags.Var(&options.Baseline, "baseline", "baseline: A report or directory of reports from a baseline run to compare test durations against")
+	flags.Float64Var(&options.BaselineFactor, "baseline-factor", 2, "baseline-factor: How many times longer than in the baseline a test must take to be listed as slower")
+	flags.DurationVar(&options.BaselineMinimum, "baseline-minimum", time.Second, "baseline-minimum: How much longer than in the baseline a test must take to be listed as slower")
+	flags.Var(&options.Coverage, "coverage", "coverage: A cobertura xml or lcov coverage report to summarize alongside the test results")
+	flags.Var(&options.CoverageBaseline, "coverage-baseline", "coverage-baseline: A cobertura xml or lcov coverage report from a baseline run to compare coverage against")
+	flags.Var(&options.Benchmarks, "benchmarks", "benchmarks: The output of go test -bench, or the json results of jmh or pytest-benchmark, to list alongside the test results")
+	flags.Var(&options.BenchmarkBaseline, "benchmark-baseline", "benchmark-baseline: Benchmark results from a baseline run to compare benchmarks against")
+	flags.Float64Var(&options.BenchmarkThreshold, "benchmark-threshold", 10, "benchmark-threshold: How many percent slower than in the baseline a benchmark must be to be flagged as a regression")
+	flags.StringVar(&options.Quarantine, "quarantine", "", "quarantine: A file listing the ids of known-flaky tests, whose failures are reported separately and do not fail the run")
+	flags.StringVar(&options.Owners, "owners", "", "owners: A file mapping test ids or files to their owners, for the tests that do not name an owner or team property")
+	flags.BoolVar(&options.MentionOwners, "mention-owners", false, "mention-owners: Whether to mention the owners of failing tests in the comment")
+	flags.BoolVar(&options.Reproduce, "reproduce", false, "reproduce: Whether to list the command that runs each failing test alone, for go, python, and java tests")
+	flags.Var(&options.ReproduceTemplates, "reproduce-template", "reproduce-template: A text/template for the command that runs a failing test of a framework alone, such as java='./gradlew test --tests {{ quote .Class }}.{{ quote .Method }}' (go, java, or python)")
+	flags.StringVar(&options.ElasticsearchUrl, "elasticsearch-url", "", "elasticsearch-url: An elasticsearch or opensearch url to index test results in")
+	flags.StringVar(&options.ElasticsearchIndex, "elasticsearch-index", "xunit-to-github", "elasticsearch-index: The index to store test results in")
+	flags.StringVar(&options.CheckRun, "check-run", "", "check-run: A name to create a github check run under, with an annotation for each failure located in the repository")
+	flags.IntVar(&options.MaxAnnotations, "max-annotations", 500, "max-annotations: The most failures to annotate the check run with, noting how many more there were")
+	flags.Var(&options.AnnotationLevels, "annotation-level", "annotation-level: The level to annotate failures of a status at, such as quarantined=warning (failed, error, flaky, or quarantined, at failure, warning, notice, or none)")
+	flags.Var(&options.CheckConclusions, "check-conclusion", "check-conclusion: The conclusion of the check run for an outcome of the run, such as skipped=neutral (passed, failed, flaky, quarantined, or skipped, as success, failure, neutral, skipped, or action_required)")
+	flags.BoolVar(&options.ChangedFilesOnly, "changed-files-only", false, "changed-files-only: Whether to only annotate failures located in files the pull request changes, listing the rest in the check run summary")
+	flags.BoolVar(&options.Teamcity, "teamcity", false, "teamcity: Whether to print teamcity service messages for each test")
+	flags.BoolVar(&options.FailOnFailure, "fail-on-failure", false, "fail-on-failure: Whether to exit non-zero when any test fails or errors")
+	options.Thresholds = addThresholdFlags(flags)
+	options.Filter = addFilterFlags(flags)
+	addOutputFlags(flags, &options.Output, &options.TemplateFile, "tap")
+	options.Time = addTimeFlags(flags)
+	flags.BoolVar(&options.ShowAssertions, "show-assertions", false, "show-assertions: Whether to include how many assertions the tests checked, when the reports count them")
+	flags.Var(&options.RequireSuites, "require-suite", "require-suite: The name of a suite that must be in the reports, where * matches any characters, failing the run when it is missing")
+	flags.StringVar(&options.EmptyReport, "empty-report", "ignore", "empty-report: What to do when no test results are found (ignore, warn, or fail)")
+	flags.StringVar(&options.Export, "export", "", "export: A file to write the prepared comment to rather than posting it, for the post command to send later")
+	flags.DurationVar(&options.Deadline, "deadline", 0, "deadline: How long the whole run may take, including parsing and publishing, after which the results parsed so far are published, or 0 for no deadline")
+	flags.Var(&options.Publish, "publish", "publish: A publisher to send results to ("+strings.Join(publisherNames(), ", ")+"), defaulting to every configured publisher")
+	flags.BoolVar(&options.Watch, "watch", false, "watch: Whether to re-render reports as they change until interrupted, then publish them")
+	flags.DurationVar(&options.WatchInterval, "watch-interval", 5*time.Second, "watch-interval: How often to check for new or modified reports when watching")
+	flags.BoolVar(&options.WatchComment, "watch-comment", false, "watch-comment: Whether to post and update the comment each time reports change when watching")
+	options.Parsing = addParsingFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// decorate adds the title, job url, and when the run started to the body of
+// the comment, along with how many assertions were checked when they are
+// shown, mentions of the owners of failures, and the embedded metadata
+func (o *reportOptions) decorate(body string, results junit.Report, location *time.Location) string {
+	return o.decorateAs(body, results, location, o.Comment.Title)
+}
+
+// decorateAs decorates the body as decorate does, under another title
+func (o *reportOptions) decorateAs(body string, results junit.Report, location *time.Location, title string) string {
+	header := render.Started(results, location, o.Time.Format)
+	if o.ShowAssertions {
+		header += render.Assertions(results.Summary)
+	}
+	if o.MentionOwners {
+		body += render.Mentions(junit.FailuresByOwner(results))
+	}
+	if o.EmbedMetadata {
+		commit, runId := o.Commit, o.RunId
+		if commit == "" {
+			commit = detectCommit()
+		}
+		if runId == "" {
+			runId = detectRunId()
+		}
+		metadata := render.NewMetadata(results, commit, runId)
+		metadata.Key = o.Comment.CommentKey
+		body += metadata.Embed()
+	}
+	return render.Decorate(header+body, title, o.Comment.JobUrl)
+}
+
+// runReport parses xml reports, posts them as a comment, and sends them to
+// every configured publisher
+func runReport(args []string) {
+	flags, options := newReportFlags()
+	parseFlags(flags, args, true)
+	ctx := context.Background()
+
+	if options.Version {
+		fmt.Println(versionString())
+		return
+	}
+
+	options.Comment.conclusions = options.CheckConclusions
+	options.Thresholds.FailOnFailure = options.FailOnFailure
+
+	if options.EmptyReport != "ignore" && options.EmptyReport != "warn" && options.EmptyReport != "fail" {
+		logger.Fatal(exitConfigError, "invalid empty-report", "error", fmt.Errorf("unknown empty-report: %s", options.EmptyReport))
+	}
+
+	if options.HistoryWindow < 0 {
+		logger.Fatal(exitConfigError, "invalid history-window", "error", fmt.Errorf("history window must be at least 1, or 0 to skip reading the history, got %d", options.HistoryWindow))
+	}
+
+	if commandLineFlags(flags)["upload-presign-expiry"] {
+		if err := validatePresignExpiry(options.UploadPresignExpiry); err != nil {
+			logger.Fatal(exitConfigError, "invalid upload-presign-expiry", "error", err)
+		}
+	}
+
+	if options.Datadog && os.Getenv("DD_API_KEY") == "" {
+		logger.Fatal(exitConfigError, "invalid datadog", "error", fmt.Errorf("DD_API_KEY is not set"))
+	}
+
+	filter, err := options.Filter.compile()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid filter", "error", err)
+	}
+
+	quarantine, err := readQuarantine(options.Quarantine)
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid quarantine list", "error", err)
+	}
+	owners, err := readOwners(options.Owners)
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid owners file", "error", err)
+	}
+	reproducer, err := junit.NewReproducer(options.ReproduceTemplates)
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid reproduce template", "error", err)
+	}
+
+	location, err := options.Time.location()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid timezone", "error", err)
+	}
+
+	renderer, err := newRenderer(options.Output, options.TemplateFile, render.Options{SkipOk: options.SkipOk, Title: options.Comment.Title, JobUrl: options.Comment.JobUrl, Location: location, TimeFormat: options.Time.Format, Assertions: options.ShowAssertions, Tree: options.Tree})
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid output", "error", err)
+	}
+
+	var summary junit.Summary
+	var commentUrl string
+	var missingSuites []string
+	var parseErrors int
+	var console io.Writer = os.Stdout
+	if options.Quiet {
+		console = ioutil.Discard
+		logger.Quiet()
+	}
+
+	defer func() {
+		exitCode := 0
+		if (options.FailOnFailure || gating(flags)) && !options.Thresholds.Passed(summary) {
+			for _, violation := range options.Thresholds.Violations(summary) {
+				logger.Error("tests failed", "reason", violation)
+			}
+			exitCode = exitTestsFailed
+		}
+		for _, name := range missingSuites {
+			logger.Error("required suite not found", "suite", name)
+			exitCode = exitTestsFailed
+		}
+		if (options.FailOnFailure || gating(flags)) && parseErrors > 0 {
+			logger.Error("reports could not be parsed", "count", parseErrors)
+			exitCode = exitParseError
+		}
+
+		if err := writeActionsOutputs(summary, commentUrl); err != nil {
+			logger.Warn("could not write github actions outputs", "error", err)
+		}
+
+		if options.Quiet {
+			line := summary.String()
+			if options.ShowAssertions && summary.Assertions > 0 {
+				line += fmt.Sprintf(", %d assertions", summary.Assertions)
+			}
+			fmt.Println(line)
+		}
+		if exitCode != 0 || atomic.LoadInt32(&interrupted) == 1 {
+			exit(exitCode)
+		}
+	}()
+
+	if options.Watch {
+		watchReports(flags.Args(), options.Parsing, options.WatchInterval, func(files []string) {
+			logger.Info("reports changed", "count", len(files))
+			results, err := options.Parsing.parseFiles(ctx, files)
+			if err != nil {
+				logger.Error("could not parse reports", "error", err)
+				return
+			}
+
+			results = junit.FilterReport(junit.AssignOwners(junit.MarkQuarantined(junit.MarkFlaky(results), quarantine), owners), filter)
+			if options.Reproduce {
+				results = junit.AddReproduceCommands(results, reproducer)
+			}
+			if err := renderer.Render(console, results); err != nil {
+				logger.Error("could not render results", "error", err)
+			}
+
+			body := render.Markdown{SkipOk: options.SkipOk, Tree: options.Tree}.Body(results, ioutil.Discard)
+			if !options.WatchComment || body == "" {
+				return
+			}
+			if options.SummaryOnly {
+				body = render.Totals(results.Summary)
+			}
+
+			body = options.decorate(body, results, location)
+			if _, err := postComment(ctx, options.Comment, &results.Summary, options.Thresholds.Passed(results.Summary), body); err != nil {
+				logger.Error("could not post comment", "error", err)
+			}
+		})
+	}
+
+	// watching stops at the first interrupt, so only interrupts after it
+	// cancel parsing and publishing the final results
+	ctx, stop := interruptContext(ctx)
+	defer stop()
+
+	parseCtx := ctx
+	if options.Deadline > 0 {
+		var cancel, cancelParse context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Deadline)
+		defer cancel()
+		parseCtx, cancelParse = context.WithTimeout(ctx, options.Deadline-options.Deadline/deadlinePublishShare)
+		defer cancelParse()
+	}
+
+	files, missing, err := options.Parsing.findFiles(flags.Args())
+	if err != nil {
+		logger.Fatal(exitParseError, "could not find reports", "error", err)
+	}
+	logger.Debug("found reports", "count", len(files))
+
+	if options.Branch == "" {
+		options.Branch = detectBranch()
+	}
+	if options.Commit == "" {
+		options.Commit = detectCommit()
+	}
+
+	results, err := options.Parsing.parseFiles(parseCtx, files)
+	if err != nil && parseCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		logger.Warn("deadline reached, publishing the results parsed so far", "unparsed", len(results.Unparsed))
+	} else if err != nil {
+		logger.Fatal(exitParseError, "could not parse reports", "error", err)
+	}
+	results.MissingPaths = missing
+	results.MissingSuites, err = junit.MissingSuites(results, options.RequireSuites)
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid required suite", "error", err)
+	}
+	missingSuites = results.MissingSuites
+	parseErrors = len(results.ParseErrors)
+	results = junit.FilterReport(junit.AssignOwners(junit.MarkQuarantined(junit.MarkFlaky(results), quarantine), owners), filter)
+	if options.HistoryDb != "" && options.HistoryWindow > 0 {
+		results = annotateHistory(ctx, historyDB{location: options.HistoryDb}, options.Comment.RepositorySlug, options.HistoryWindow, results)
+	}
+	if options.ComparePrevious {
+		if failing, ok := previousFailures(ctx, options.Comment); ok {
+			results = junit.MarkFailingSince(results, failing)
+		}
+	}
+	if options.Reproduce {
+		results = junit.AddReproduceCommands(results, reproducer)
+	}
+	if len(options.Baseline) > 0 {
+		baselineFiles, _, err := options.Parsing.findFiles(options.Baseline)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not find baseline reports", "error", err)
+		}
+		baseline, err := options.Parsing.parseFiles(ctx, baselineFiles)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not parse baseline reports", "error", err)
+		}
+		results.SlowerTests = junit.CompareDurations(baseline, results, options.BaselineFactor, options.BaselineMinimum)
+	}
+	if len(options.Coverage) > 0 {
+		coverage, err := readCoverage(options.Coverage, options.Parsing.sourceRoot())
+		if err != nil {
+			logger.Fatal(exitParseError, "could not parse coverage", "error", err)
+		}
+		if len(options.CoverageBaseline) > 0 {
+			baseline, err := readCoverage(options.CoverageBaseline, options.Parsing.sourceRoot())
+			if err != nil {
+				logger.Fatal(exitParseError, "could not parse baseline coverage", "error", err)
+			}
+			coverage = junit.CompareCoverage(baseline, coverage)
+		}
+		results.Coverage = &coverage
+	}
+	if len(options.Benchmarks) > 0 {
+		benchmarks, err := readBenchmarks(options.Benchmarks)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not parse benchmarks", "error", err)
+		}
+		if len(options.BenchmarkBaseline) > 0 {
+			baseline, err := readBenchmarks(options.BenchmarkBaseline)
+			if err != nil {
+				logger.Fatal(exitParseError, "could not parse baseline benchmarks", "error", err)
+			}
+			benchmarks = junit.CompareBenchmarks(baseline, benchmarks, options.BenchmarkThreshold)
+		}
+		results.Benchmarks = benchmarks
+	}
+	if options.UploadUrl != "" {
+		results, err = uploadAttachments(ctx, options.UploadUrl, options.UploadEndpoint, options.UploadPresignExpiry, options.Parsing.sourceRoot(), results)
+		if err != nil {
+			logger.Fatal(exitPublishError, "could not upload attachments", "error", err)
+		}
+	}
+	summary = results.Summary
+	testsuites := results.Suites
+	if err := renderer.Render(console, results); err != nil {
+		logger.Fatal(exitConfigError, "could not render results", "error", err)
+	}
+	body := render.Markdown{SkipOk: options.SkipOk, Tree: options.Tree}.Body(results, ioutil.Discard)
+
+	if options.Teamcity {
+		writeTeamcityMessages(os.Stdout, testsuites)
+	}
+
+	if summary.Tests == 0 {
+		switch options.EmptyReport {
+		case "warn":
+			logger.Warn("no test results found")
+			body = render.NoResults() + body
+		case "fail":
+			logger.Fatal(exitTestsFailed, "no test results found")
+		}
+	}
+
+	if body == "" {
+		return
+	}
+
+	// the full body is still uploaded when only the totals are commented,
+	// with the job linked to when the report is not uploaded
+	report := body
+	if options.SummaryOnly {
+		body = render.Totals(summary)
+		if options.Comment.JobUrl == "" {
+			options.Comment.JobUrl = detectJobUrl()
+		}
+	}
+
+	reportUrl := ""
+	if options.UploadUrl != "" {
+		run := options.RunId
+		if run == "" {
+			run = detectRunId()
+		}
+		if run == "" {
+			run = options.Commit
+		}
+		if run == "" {
+			run = detectCommit()
+		}
+		reportUrl, err = uploadReports(ctx, options.UploadUrl, options.UploadEndpoint, options.UploadPresignExpiry, run, options.Comment.Title, summary, testsuites, report)
+		if err != nil {
+			logger.Fatal(exitPublishError, "could not upload reports", "error", err)
+		}
+		body = fmt.Sprintf("[Full Report](%s)", reportUrl) + "\n\n" + body
+	}
+
+	body = options.decorate(body, results, location)
+
+	session := &publishSession{
+		options:   options,
+		location:  location,
+		reportUrl: reportUrl,
+		history:   historyRun{Repository: options.Comment.RepositorySlug, Branch: options.Branch, Commit: options.Commit, PullRequest: options.Comment.PullRequestId},
+	}
+	publishers, err := selectPublishers(session, options.Publish)
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid publisher", "error", err)
+	}
+
+	for _, publisher := range publishers {
+		if err := publisher.publisher.Publish(ctx, results, body); err != nil {
+			logger.Fatal(exitPublishError, "could not publish results", "publisher", publisher.name, "error", err)
+		}
+	}
+	commentUrl = session.commentUrl
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/github"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+type commentOptions struct {
+	Provider         string
+	RepositorySlug   string
+	PullRequestId    int
+	Title            string
+	JobUrl           string
+	GitlabUrl        string
+	GiteaUrl         string
+	AzureDevopsUrl   string
+	GerritUrl        string
+	GerritLabel      string
+	BuildkiteContext string
+	BitbucketReport  bool
+	GhAuth           bool
+	TokenCommand     string
+	VaultPath        string
+	VaultField       string
+	CheckRunFallback bool
+	SkipLabels       stringSlice
+	CommentKey       string
+
+	// commentId is the comment posted by a previous call, which later calls
+	// edit rather than posting another comment
+	commentId int64
+	// marker is a hidden html comment added to the body that identifies the
+	// comment on github, so that an earlier run's comment with it is edited
+	// rather than posting another. It defaults to the marker of CommentKey
+	// when that is set.
+	marker string
+	// conclusions are the conclusions of the check runs that results too long
+	// for a comment are published as instead
+	conclusions checkConclusions
+}
+
+func addCommentFlags(flags *flag.FlagSet) *commentOptions {
+	options := &commentOptions{}
+	flags.StringVar(&options.Title, "title", "", "title: A title for the comment")
+	flags.StringVar(&options.JobUrl, "job-url", "", "job-url: A url for the report")
+	flags.IntVar(&options.PullRequestId, "pull-request-id", 0, "pull-request-id: A pull request ID")
+	flags.StringVar(&options.RepositorySlug, "repository-slug", "", "repository-slug: The slug of the repository")
+	flags.StringVar(&options.Provider, "provider", "", "provider: The service to post the comment to (github, gitlab, bitbucket, gitea, azure, gerrit, buildkite)")
+	flags.StringVar(&options.GitlabUrl, "gitlab-url", "", "gitlab-url: The base url of the gitlab instance")
+	flags.StringVar(&options.GiteaUrl, "gitea-url", "", "gitea-url: The base url of the gitea or forgejo instance")
+	flags.StringVar(&options.AzureDevopsUrl, "azure-devops-url", "", "azure-devops-url: The collection url of the azure devops organization")
+	flags.StringVar(&options.GerritUrl, "gerrit-url", "", "gerrit-url: The base url of the gerrit instance")
+	flags.StringVar(&options.GerritLabel, "gerrit-label", "", "gerrit-label: A gerrit label to vote on based on test failures, such as Verified")
+	flags.StringVar(&options.BuildkiteContext, "buildkite-context", "xunit-to-github", "buildkite-context: The context to annotate buildkite builds under")
+	flags.BoolVar(&options.BitbucketReport, "bitbucket-report", false, "bitbucket-report: Whether to also create a bitbucket code insights report")
+	flags.StringVar(&options.TokenCommand, "token-command", "", "token-command: A shell command that prints the github token, such as a credential helper")
+	flags.StringVar(&options.VaultPath, "vault-path", "", "vault-path: The path of a hashicorp vault secret holding the github token, such as secret/data/ci/github")
+	flags.StringVar(&options.VaultField, "vault-field", "token", "vault-field: The field of the vault secret holding the github token")
+	flags.BoolVar(&options.CheckRunFallback, "check-run-fallback", true, "check-run-fallback: Whether to publish results too long for a github comment as check runs, commenting with links to them")
+	flags.StringVar(&options.CommentKey, "comment-key", "", "comment-key: A key, such as unit or e2e, that the comment on a github pull request is edited under by later runs with the same key, rather than posting another")
+	flags.Var(&options.SkipLabels, "skip-label", "skip-label: A github pull request label, such as no-test-report, that skips commenting and creating check runs on the pull requests that have it")
+	flags.BoolVar(&options.GhAuth, "gh-auth", true, "gh-auth: Whether to post to github with the token the gh cli is logged in with when GITHUB_ACCESS_TOKEN is not set")
+	return options
+}
+
+func detectProvider() string {
+	if os.Getenv("GITLAB_CI") == "true" {
+		return "gitlab"
+	} else if os.Getenv("BITBUCKET_BUILD_NUMBER") != "" {
+		return "bitbucket"
+	} else if os.Getenv("GITEA_ACTIONS") == "true" {
+		return "gitea"
+	} else if os.Getenv("TF_BUILD") == "True" {
+		return "azure"
+	} else if os.Getenv("GERRIT_CHANGE_NUMBER") != "" {
+		return "gerrit"
+	} else if os.Getenv("BUILDKITE") == "true" {
+		return "buildkite"
+	}
+	return "github"
+}
+
+// postComment posts the body to the configured provider, returning the url of
+// the comment when the provider reports one. Nothing is posted when the
+// credentials or pull request for the provider are missing. Features that
+// depend on test results, such as label votes, are skipped when summary is nil,
+// and otherwise use passed as the conclusion of the run.
+func postComment(ctx context.Context, options *commentOptions, summary *junit.Summary, passed bool, body string) (string, error) {
+	if options.Provider == "" {
+		options.Provider = detectProvider()
+	}
+
+	var err error
+	posted := false
+	commentUrl := ""
+	switch options.Provider {
+	case "github":
+		var accessToken string
+		accessToken, _, err = githubAccessToken(ctx, options)
+		if err != nil {
+			return "", err
+		}
+		if accessToken == "" || options.PullRequestId == 0 || options.RepositorySlug == "" {
+			break
+		}
+
+		client := &github.Client{HTTPClient: httpClient, BaseURL: os.Getenv("GITHUB_API_URL"), AccessToken: accessToken}
+		if len(options.SkipLabels) > 0 {
+			var pullRequest github.PullRequest
+			pullRequest, err = client.PullRequest(ctx, options.RepositorySlug, options.PullRequestId)
+			if err != nil {
+				break
+			}
+			if label := skipLabel(pullRequest, options.SkipLabels); label != "" {
+				logger.Info("comment skipped", "label", label)
+				break
+			}
+		}
+
+		posted = true
+		marker := options.marker
+		if marker == "" && options.CommentKey != "" {
+			marker = commentMarker(options.CommentKey)
+		}
+		suffix := ""
+		if marker != "" {
+			suffix = marker + "\n"
+		}
+		// the marker is counted towards the length of the comment it ends
+		if options.CheckRunFallback && len(body)+len(suffix) > github.MaxCommentLength {
+			body, err = publishCheckRuns(ctx, client, options, summary, passed, body)
+			if err != nil {
+				return "", err
+			}
+		}
+		body += suffix
+		if marker != "" {
+			if options.commentId == 0 {
+				options.commentId = findComment(ctx, client, options.RepositorySlug, options.PullRequestId, marker)
+			}
+		}
+		var comment github.Comment
+		comment, err = client.PostComment(ctx, options.RepositorySlug, options.PullRequestId, options.commentId, body)
+		options.commentId, commentUrl = comment.Id, comment.HtmlUrl
+	case "gitlab":
+		gitlabAccessToken := os.Getenv("GITLAB_ACCESS_TOKEN")
+		if options.GitlabUrl == "" {
+			options.GitlabUrl = getenvDefault("CI_SERVER_URL", "https://gitlab.com")
+		}
+		if options.RepositorySlug == "" {
+			options.RepositorySlug = getenvDefault("CI_PROJECT_PATH", os.Getenv("CI_PROJECT_ID"))
+		}
+		if options.PullRequestId == 0 {
+			options.PullRequestId, _ = strconv.Atoi(os.Getenv("CI_MERGE_REQUEST_IID"))
+		}
+		if gitlabAccessToken == "" || options.PullRequestId == 0 || options.RepositorySlug == "" {
+			break
+		}
+
+		posted = true
+		err = postGitlabComment(ctx, options.GitlabUrl, options.RepositorySlug, options.PullRequestId, gitlabAccessToken, body)
+	case "gitea", "forgejo":
+		giteaAccessToken := os.Getenv("GITEA_ACCESS_TOKEN")
+		if options.GiteaUrl == "" {
+			options.GiteaUrl = os.Getenv("GITHUB_SERVER_URL")
+		}
+		if giteaAccessToken == "" || options.GiteaUrl == "" || options.PullRequestId == 0 || options.RepositorySlug == "" {
+			break
+		}
+
+		posted = true
+		var comment giteaComment
+		comment, err = postGiteaComment(ctx, options.GiteaUrl, options.RepositorySlug, options.PullRequestId, giteaAccessToken, options.commentId, body)
+		options.commentId, commentUrl = comment.Id, comment.HtmlUrl
+	case "azure":
+		credentials := azureCredentials()
+		if options.AzureDevopsUrl == "" {
+			options.AzureDevopsUrl = os.Getenv("SYSTEM_COLLECTIONURI")
+		}
+		if options.RepositorySlug == "" && os.Getenv("SYSTEM_TEAMPROJECT") != "" {
+			options.RepositorySlug = os.Getenv("SYSTEM_TEAMPROJECT") + "/" + getenvDefault("BUILD_REPOSITORY_ID", os.Getenv("BUILD_REPOSITORY_NAME"))
+		}
+		if options.PullRequestId == 0 {
+			options.PullRequestId, _ = strconv.Atoi(os.Getenv("SYSTEM_PULLREQUEST_PULLREQUESTID"))
+		}
+		if credentials == "" || options.AzureDevopsUrl == "" || options.PullRequestId == 0 || options.RepositorySlug == "" {
+			break
+		}
+
+		posted = true
+		marker := options.marker
+		if marker == "" && options.CommentKey != "" {
+			marker = commentMarker(options.CommentKey)
+		}
+		err = postAzureThread(ctx, options.AzureDevopsUrl, options.RepositorySlug, options.PullRequestId, credentials, marker, body)
+	case "gerrit":
+		credentials := gerritCredentials()
+		if options.GerritUrl == "" {
+			options.GerritUrl = os.Getenv("GERRIT_URL")
+		}
+		if options.PullRequestId == 0 {
+			options.PullRequestId, _ = strconv.Atoi(os.Getenv("GERRIT_CHANGE_NUMBER"))
+		}
+		if credentials == "" || options.GerritUrl == "" || options.PullRequestId == 0 {
+			break
+		}
+
+		label := options.GerritLabel
+		if summary == nil {
+			label = ""
+		}
+
+		posted = true
+		err = postGerritReview(ctx, options.GerritUrl, options.PullRequestId, os.Getenv("GERRIT_PATCHSET_REVISION"), credentials, label, passed, body)
+	case "buildkite":
+		posted = true
+		err = postBuildkiteAnnotation(ctx, options.BuildkiteContext, summary == nil || passed, body)
+	case "bitbucket":
+		credentials := bitbucketCredentials()
+		if options.RepositorySlug == "" && os.Getenv("BITBUCKET_REPO_FULL_NAME") != "" {
+			options.RepositorySlug = os.Getenv("BITBUCKET_REPO_FULL_NAME")
+		}
+		if options.RepositorySlug == "" && os.Getenv("BITBUCKET_REPO_SLUG") != "" {
+			options.RepositorySlug = os.Getenv("BITBUCKET_WORKSPACE") + "/" + os.Getenv("BITBUCKET_REPO_SLUG")
+		}
+		if options.PullRequestId == 0 {
+			options.PullRequestId, _ = strconv.Atoi(os.Getenv("BITBUCKET_PR_ID"))
+		}
+		if credentials == "" || options.RepositorySlug == "" {
+			break
+		}
+
+		if options.BitbucketReport && summary != nil && os.Getenv("BITBUCKET_COMMIT") != "" {
+			err = postBitbucketReport(ctx, options.RepositorySlug, os.Getenv("BITBUCKET_COMMIT"), credentials, options.Title, *summary, passed, options.JobUrl)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if options.PullRequestId == 0 {
+			break
+		}
+
+		posted = true
+		err = postBitbucketComment(ctx, options.RepositorySlug, options.PullRequestId, credentials, body)
+	default:
+		return "", fmt.Errorf("unknown provider: %s", options.Provider)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if posted {
+		logger.Info("comment posted", "provider", options.Provider)
+	}
+
+	return commentUrl, nil
+}
+
+// skipLabel is the first of the labels the pull request has, or empty when it
+// has none of them
+func skipLabel(pullRequest github.PullRequest, labels []string) string {
+	for _, label := range labels {
+		if pullRequest.HasLabel(label) {
+			return label
+		}
+	}
+	return ""
+}
+
+// findComment is the id of the latest comment on the pull request with the
+// marker, or 0 when there is none. Failing to read the comments is warned
+// about, posting another comment instead.
+func findComment(ctx context.Context, client *github.Client, repositorySlug string, pullRequestId int, marker string) int64 {
+	comments, err := client.Comments(ctx, repositorySlug, pullRequestId)
+	if err != nil {
+		logger.Warn("could not read previous comments", "error", err)
+		return 0
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		if strings.Contains(comments[i].Body, marker) {
+			return comments[i].Id
+		}
+	}
+	return 0
+}
+
+// commentMarker identifies the comment posted with --comment-key
+func commentMarker(key string) string {
+	return "<!-- xunit-to-github-comment " + url.QueryEscape(key) + " -->"
+}
+
+// suiteMarker identifies the comment of a group of suites posted with
+// --comment-per-suite, within the comments of the --comment-key when it is
+// set
+func suiteMarker(key string, name string) string {
+	if key == "" {
+		return "<!-- xunit-to-github-suite " + url.QueryEscape(name) + " -->"
+	}
+	return "<!-- xunit-to-github-suite " + url.QueryEscape(key) + " " + url.QueryEscape(name) + " -->"
+}
+
+// postSuiteComments posts a comment for each group of suites of the results,
+// as split by junit.GroupReport, titled with the name of the group and
+// marked so that the comments of later runs edit them. The url of the first
+// comment is returned.
+func postSuiteComments(ctx context.Context, session *publishSession, results junit.Report) (string, error) {
+	options := session.options
+	firstUrl := ""
+	for _, group := range junit.GroupReport(results) {
+		body := render.Markdown{SkipOk: options.SkipOk, Tree: options.Tree}.Body(group.Report, ioutil.Discard)
+		if options.SummaryOnly {
+			body = render.Totals(group.Report.Summary)
+		}
+		if session.reportUrl != "" {
+			body = fmt.Sprintf("[Full Report](%s)", session.reportUrl) + "\n\n" + body
+		}
+
+		name := group.Name
+		if name == "" {
+			name = "unlabeled"
+		}
+		comment := *options.Comment
+		comment.Title = name
+		if options.Comment.Title != "" {
+			comment.Title = options.Comment.Title + ": " + name
+		}
+		comment.marker = suiteMarker(options.Comment.CommentKey, group.Name)
+		body = options.decorateAs(body, group.Report, session.location, comment.Title)
+
+		commentUrl, err := postComment(ctx, &comment, &group.Report.Summary, options.Thresholds.Passed(group.Report.Summary), body)
+		if err != nil {
+			return firstUrl, err
+		}
+		if firstUrl == "" {
+			firstUrl = commentUrl
+		}
+	}
+	return firstUrl, nil
+}
+
+// previousFailures reads the results embedded in the latest comments on the
+// pull request that have them, returning the commit each testcase they
+// failed has failed on since, and reporting whether any were found. The
+// comments of the same run, such as those of --comment-per-suite, are read
+// together, and only the comments of the same --comment-key are read. Only
+// github comments are read, and failing to read them is
+// warned about rather than failing the run.
+func previousFailures(ctx context.Context, options *commentOptions) (map[string]string, bool) {
+	provider := options.Provider
+	if provider == "" {
+		provider = detectProvider()
+	}
+	if provider != "github" || options.PullRequestId == 0 || options.RepositorySlug == "" {
+		return nil, false
+	}
+	accessToken, _, err := githubAccessToken(ctx, options)
+	if err != nil || accessToken == "" {
+		return nil, false
+	}
+
+	client := &github.Client{HTTPClient: httpClient, BaseURL: os.Getenv("GITHUB_API_URL"), AccessToken: accessToken}
+	comments, err := client.Comments(ctx, options.RepositorySlug, options.PullRequestId)
+	if err != nil {
+		logger.Warn("could not read previous comments", "error", err)
+		return nil, false
+	}
+
+	var failing map[string]string
+	run := ""
+	for i := len(comments) - 1; i >= 0; i-- {
+		metadata, ok := render.ParseMetadata(comments[i].Body)
+		if !ok || metadata.Key != options.CommentKey {
+			continue
+		}
+		key := metadata.RunId + "@" + metadata.Commit
+		if failing != nil && (key != run || key == "@") {
+			continue
+		}
+		if failing == nil {
+			failing, run = map[string]string{}, key
+		}
+		for id, since := range metadata.Failing() {
+			failing[id] = since
+		}
+	}
+	return failing, failing != nil
+}
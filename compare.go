@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+type compareOptions struct {
+	Format          string
+	BaselineFactor  float64
+	BaselineMinimum time.Duration
+	Filter          *filterOptions
+	Parsing         *parsingOptions
+}
+
+func newCompareFlags() (*flag.FlagSet, *compareOptions) {
+	flags := flag.NewFlagSet("xunit-to-github compare", flag.ExitOnError)
+	options := &compareOptions{}
+	flags.StringVar(&options.Format, "format", "markdown", "format: The format to write the comparison in (markdown or json)")
+	flags.Float64Var(&options.BaselineFactor, "baseline-factor", 2, "baseline-factor: How many times longer than in the old run a test must take to be listed as slower")
+	flags.DurationVar(&options.BaselineMinimum, "baseline-minimum", time.Second, "baseline-minimum: How much longer than in the old run a test must take to be listed as slower")
+	options.Filter = addFilterFlags(flags)
+	options.Parsing = addParsingFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runCompare diffs the xml reports of an old and a new run
+func runCompare(args []string) {
+	flags, options := newCompareFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	if options.Format != "markdown" && options.Format != "json" {
+		logger.Fatal(exitConfigError, "invalid format", "error", fmt.Errorf("unknown format: %s", options.Format))
+	}
+	if flags.NArg() != 2 {
+		logger.Fatal(exitConfigError, "invalid arguments", "error", fmt.Errorf("compare requires an old and a new report path, got %d", flags.NArg()))
+	}
+
+	filter, err := options.Filter.compile()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid filter", "error", err)
+	}
+
+	var runs []junit.Report
+	for _, path := range flags.Args() {
+		files, missing, err := options.Parsing.findFiles([]string{path})
+		if err != nil {
+			logger.Fatal(exitParseError, "could not find reports", "error", err)
+		}
+
+		results, err := options.Parsing.parseFiles(ctx, files)
+		if err != nil {
+			logger.Fatal(exitParseError, "could not parse reports", "error", err)
+		}
+		results.MissingPaths = missing
+		runs = append(runs, junit.FilterReport(results, filter))
+	}
+
+	comparison := junit.Compare(runs[0], runs[1], options.BaselineFactor, options.BaselineMinimum)
+	if options.Format == "json" {
+		err = writeJSON(os.Stdout, comparison)
+	} else {
+		_, err = fmt.Fprint(os.Stdout, render.Comparison(comparison))
+	}
+	if err != nil {
+		logger.Fatal(exitPublishError, "could not write comparison", "error", err)
+	}
+}
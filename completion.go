@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func flagNames(flags *flag.FlagSet) []string {
+	var names []string
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	sort.Strings(names)
+	return names
+}
+
+func subcommandNames() []string {
+	var names []string
+	for _, command := range commands[1:] {
+		names = append(names, command.Name)
+	}
+	return names
+}
+
+const bashCompletion = `_xunit_to_github() {
+  local cur flags
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  case "${COMP_WORDS[1]}" in
+%s  completion)
+    COMPREPLY=($(compgen -W "bash zsh fish" -- "${cur}"))
+    return
+    ;;
+  version)
+    return
+    ;;
+  *)
+    flags="%s"
+    if [[ ${COMP_CWORD} -eq 1 && "${cur}" != -* ]]; then
+      COMPREPLY=($(compgen -W "%s" -- "${cur}") $(compgen -f -- "${cur}"))
+      return
+    fi
+    ;;
+  esac
+  if [[ "${cur}" == -* ]]; then
+    COMPREPLY=($(compgen -W "${flags}" -- "${cur}"))
+  else
+    COMPREPLY=($(compgen -f -- "${cur}"))
+  fi
+}
+complete -o filenames -F _xunit_to_github xunit-to-github
+`
+
+// completableCommands returns the subcommands that take flags and files
+func completableCommands() []command {
+	var completable []command
+	for _, command := range commands[1:] {
+		if command.Name != "completion" && command.Name != "version" {
+			completable = append(completable, command)
+		}
+	}
+	return completable
+}
+
+func writeBashCompletion() {
+	cases := ""
+	for _, command := range completableCommands() {
+		cases += fmt.Sprintf("  %s)\n    flags=\"%s\"\n    ;;\n", command.Name, strings.Join(flagNames(command.Flags()), " "))
+	}
+	fmt.Printf(bashCompletion, cases, strings.Join(flagNames(commands[0].Flags()), " "), strings.Join(subcommandNames(), " "))
+}
+
+func writeZshCompletion() {
+	fmt.Println("#compdef xunit-to-github")
+	fmt.Println()
+	fmt.Println("_xunit_to_github() {")
+	fmt.Println("  local -a subcommands")
+	fmt.Println("  subcommands=(")
+	for _, command := range commands[1:] {
+		fmt.Printf("    '%s:%s'\n", command.Name, command.Description)
+	}
+	fmt.Println("  )")
+	fmt.Println()
+	fmt.Println("  case \"${words[2]}\" in")
+	for _, command := range completableCommands() {
+		fmt.Printf("    %s)\n      _arguments \\\n", command.Name)
+		writeZshArguments(command.Flags(), "        ")
+		fmt.Println("        '*:file:_files'")
+		fmt.Println("      ;;")
+	}
+	fmt.Println("    completion)")
+	fmt.Println("      _arguments '2:shell:(bash zsh fish)'")
+	fmt.Println("      ;;")
+	fmt.Println("    version)")
+	fmt.Println("      ;;")
+	fmt.Println("    *)")
+	fmt.Println("      _arguments \\")
+	writeZshArguments(commands[0].Flags(), "        ")
+	fmt.Println("        '1: :{_describe command subcommands; _files}' \\")
+	fmt.Println("        '*:file:_files'")
+	fmt.Println("      ;;")
+	fmt.Println("  esac")
+	fmt.Println("}")
+	fmt.Println()
+	fmt.Println("_xunit_to_github \"$@\"")
+}
+
+func writeZshArguments(flags *flag.FlagSet, indent string) {
+	flags.VisitAll(func(f *flag.Flag) {
+		fmt.Printf("%s'--%s[%s]' \\\n", indent, f.Name, zshEscape(flagDescription(f)))
+	})
+}
+
+func writeFishCompletion() {
+	subcommands := strings.Join(subcommandNames(), " ")
+	for _, command := range commands[1:] {
+		fmt.Printf("complete -c xunit-to-github -n 'not __fish_seen_subcommand_from %s' -a %s -d '%s'\n", subcommands, command.Name, fishEscape(command.Description))
+	}
+
+	commands[0].Flags().VisitAll(func(f *flag.Flag) {
+		fmt.Printf("complete -c xunit-to-github -n 'not __fish_seen_subcommand_from %s' -l %s -d '%s'\n", subcommands, f.Name, fishEscape(flagDescription(f)))
+	})
+
+	fmt.Println("complete -c xunit-to-github -n '__fish_seen_subcommand_from completion' -f -a 'bash zsh fish'")
+	for _, command := range completableCommands() {
+		command.Flags().VisitAll(func(f *flag.Flag) {
+			fmt.Printf("complete -c xunit-to-github -n '__fish_seen_subcommand_from %s' -l %s -d '%s'\n", command.Name, f.Name, fishEscape(flagDescription(f)))
+		})
+	}
+}
+
+// flagDescription strips the "name: " prefix from a flag's usage
+func flagDescription(f *flag.Flag) string {
+	return strings.TrimPrefix(f.Usage, f.Name+": ")
+}
+
+func zshEscape(value string) string {
+	return strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:").Replace(value)
+}
+
+func fishEscape(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value)
+}
+
+// runCompletion prints a completion script for the named shell
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: xunit-to-github completion bash|zsh|fish")
+		os.Exit(exitConfigError)
+	}
+
+	switch args[0] {
+	case "bash":
+		writeBashCompletion()
+	case "zsh":
+		writeZshCompletion()
+	case "fish":
+		writeFishCompletion()
+	default:
+		logger.Fatal(exitConfigError, "unsupported shell", "shell", args[0])
+	}
+}
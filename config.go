@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var configFilenames = []string{".xunit-to-github.yml", ".xunit-to-github.yaml", ".xunit-to-github.toml"}
+
+// findConfigFile searches the directory and each of its parents for a config file
+func findConfigFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		for _, name := range configFilenames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseConfigFile reads a flat yaml or toml file mapping flag names to values.
+// Lists may be written inline ([a, b]) or, in yaml, as indented "- item" lines.
+func parseConfigFile(path string) (map[string][]string, error) {
+	config := map[string][]string{}
+	file, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	separator := ":"
+	if filepath.Ext(path) == ".toml" {
+		separator = "="
+	}
+
+	currentKey := ""
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := stripConfigComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if currentKey == "" {
+				return config, fmt.Errorf("%s:%d: list item without a key", path, lineNumber)
+			}
+			config[currentKey] = append(config[currentKey], unquoteConfigValue(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+
+		parts := strings.SplitN(trimmed, separator, 2)
+		if len(parts) != 2 {
+			return config, fmt.Errorf("%s:%d: expected key%s value", path, lineNumber, separator)
+		}
+
+		currentKey = strings.Replace(strings.TrimSpace(parts[0]), "_", "-", -1)
+		value := strings.TrimSpace(parts[1])
+		config[currentKey] = []string{}
+		if value == "" {
+			continue
+		}
+
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					config[currentKey] = append(config[currentKey], unquoteConfigValue(item))
+				}
+			}
+			continue
+		}
+
+		config[currentKey] = []string{unquoteConfigValue(value)}
+	}
+
+	return config, scanner.Err()
+}
+
+func stripConfigComment(line string) string {
+	quote := rune(0)
+	for i, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteConfigValue(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1)
+	}
+	return value
+}
+
+// commandLineFlags returns the names of the flags set on the command line
+func commandLineFlags(flags *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// environmentVariable returns the XUNIT_TO_GITHUB_ variable a flag may be set with
+func environmentVariable(name string) string {
+	return "XUNIT_TO_GITHUB_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// applyEnvironment sets every flag that has not already been set from its
+// environment variable. Repeatable flags take one value per line.
+func applyEnvironment(flags *flag.FlagSet, set map[string]bool) error {
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(environmentVariable(f.Name))
+		if !ok || set[f.Name] || err != nil {
+			return
+		}
+
+		values := []string{value}
+		if _, repeatable := f.Value.(*stringSlice); repeatable {
+			values = strings.Split(strings.TrimSpace(value), "\n")
+		}
+
+		for _, value := range values {
+			if err = flags.Set(f.Name, value); err != nil {
+				err = fmt.Errorf("%s: invalid value for %s: %s", environmentVariable(f.Name), f.Name, err)
+				return
+			}
+		}
+		set[f.Name] = true
+	})
+	return err
+}
+
+// applyConfigFile sets every flag in the config file that has not already been
+// set. Keys for flags the command does not define are errors only when strict.
+func applyConfigFile(flags *flag.FlagSet, path string, set map[string]bool, strict bool) error {
+	config, err := parseConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	for key, values := range config {
+		if flags.Lookup(key) == nil || key == "config" {
+			if !strict && key != "config" {
+				continue
+			}
+			return fmt.Errorf("%s: unknown config key: %s", path, key)
+		}
+		if set[key] {
+			continue
+		}
+
+		for _, value := range values {
+			if err := flags.Set(key, value); err != nil {
+				return fmt.Errorf("%s: invalid value for %s: %s", path, key, err)
+			}
+		}
+		set[key] = true
+	}
+
+	return nil
+}
+
+// addCommonFlags adds the flags shared by every command
+func addCommonFlags(flags *flag.FlagSet) {
+	flags.String("config", "", "config: A config file to read flag values from, defaulting to the nearest .xunit-to-github.yml")
+	flags.String("log-level", "info", "log-level: The minimum level of diagnostics to log (debug, info, warn, error)")
+	flags.String("log-format", "text", "log-format: The format to log diagnostics in (text, json)")
+	flags.Duration("http-timeout", defaultHTTPTimeout, "http-timeout: How long to wait for each request to a publisher before failing, or 0 to wait forever")
+}
+
+// parseFlags parses the command line, then fills in any unset flags from the
+// environment and the config file
+func parseFlags(flags *flag.FlagSet, args []string, strict bool) {
+	// invalid flags exit as configuration errors, rather than with the code
+	// the flag package uses
+	flags.Init(flags.Name(), flag.ContinueOnError)
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(exitConfigError)
+	}
+
+	set := commandLineFlags(flags)
+	if err := applyEnvironment(flags, set); err != nil {
+		logger.Fatal(exitConfigError, "invalid environment variable", "error", err)
+	}
+
+	configFile := flags.Lookup("config").Value.String()
+	if configFile == "" {
+		configFile = findConfigFile(".")
+	}
+	if configFile != "" {
+		if err := applyConfigFile(flags, configFile, set, strict); err != nil {
+			logger.Fatal(exitConfigError, "invalid config file", "error", err)
+		}
+	}
+
+	if err := logger.Configure(flags.Lookup("log-level").Value.String(), flags.Lookup("log-format").Value.String()); err != nil {
+		logger.Fatal(exitConfigError, "invalid logging configuration", "error", err)
+	}
+	logger.Debug("configuration loaded", "config", configFile)
+
+	setHTTPTimeout(flags.Lookup("http-timeout").Value.(flag.Getter).Get().(time.Duration))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// githubAccessToken returns the token to post to github with, along with
+// where it came from. A --token-command or --vault-path is preferred, then
+// GITHUB_ACCESS_TOKEN, falling back to the token the gh cli is logged in with
+// when GhAuth is set.
+func githubAccessToken(ctx context.Context, options *commentOptions) (string, string, error) {
+	if options.TokenCommand != "" {
+		token, err := runTokenCommand(ctx, options.TokenCommand)
+		return token, "--token-command", err
+	}
+	if options.VaultPath != "" {
+		token, err := readVaultSecret(ctx, getenvDefault("VAULT_ADDR", "http://127.0.0.1:8200"), os.Getenv("VAULT_TOKEN"), options.VaultPath, options.VaultField)
+		return token, "vault " + options.VaultPath, err
+	}
+	if token := os.Getenv("GITHUB_ACCESS_TOKEN"); token != "" {
+		return token, "GITHUB_ACCESS_TOKEN", nil
+	}
+	if !options.GhAuth {
+		return "", "", nil
+	}
+
+	host := githubHost(os.Getenv("GITHUB_API_URL"))
+	if token := ghAuthToken(ctx, host); token != "" {
+		return token, "gh auth token", nil
+	}
+	if token := ghHostsToken(ghConfigDir(), host); token != "" {
+		return token, "gh hosts.yml", nil
+	}
+	return "", "", nil
+}
+
+// runTokenCommand runs the command with the shell, returning what it prints
+// as the token. Its stderr is passed through so that prompts and errors from
+// credential helpers are seen.
+func runTokenCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("token command printed nothing")
+	}
+	return token, nil
+}
+
+// readVaultSecret reads a field of a secret from hashicorp vault, such as
+// secret/data/ci/github for a kv version 2 engine or secret/ci/github for
+// version 1. VAULT_NAMESPACE is sent for vault enterprise namespaces.
+func readVaultSecret(ctx context.Context, address string, vaultToken string, path string, field string) (string, error) {
+	if vaultToken == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set")
+	}
+
+	headers := map[string]string{
+		"X-Vault-Token": vaultToken,
+	}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		headers["X-Vault-Namespace"] = namespace
+	}
+
+	responseBody, err := sendRequest(ctx, "GET", strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), headers, nil, 200)
+	if err != nil {
+		return "", fmt.Errorf("could not read vault secret %s: %w", path, err)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(responseBody, &secret); err != nil {
+		return "", err
+	}
+
+	// kv version 2 nests the fields of the secret beneath its metadata
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+
+	token, ok := data[field].(string)
+	if !ok || token == "" {
+		return "", fmt.Errorf("vault secret %s has no %s field", path, field)
+	}
+	return token, nil
+}
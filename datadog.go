@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+const datadogMaxEvents = 50
+
+func postDatadog(ctx context.Context, site string, apiKey string, tags []string, summary junit.Summary, testsuites []junit.Suite) error {
+	baseUrl := fmt.Sprintf("https://api.%s", strings.TrimPrefix(site, "api."))
+	headers := map[string]string{
+		"DD-API-KEY": apiKey,
+	}
+
+	now := time.Now().Unix()
+	var series []map[string]interface{}
+	gauge := func(metric string, value float64, tags []string) {
+		series = append(series, map[string]interface{}{
+			"metric": metric,
+			"type":   3,
+			"points": []map[string]interface{}{{"timestamp": now, "value": value}},
+			"tags":   tags,
+		})
+	}
+
+	for _, testsuite := range testsuites {
+		suiteTags := append([]string{"suite:" + testsuite.Name}, tags...)
+		gauge("xunit.tests", float64(testsuite.Tests), suiteTags)
+		gauge("xunit.failures", float64(testsuite.Failures), suiteTags)
+		gauge("xunit.errors", float64(testsuite.Errors), suiteTags)
+		gauge("xunit.skipped", float64(testsuite.Skipped), suiteTags)
+		gauge("xunit.duration", testsuite.Time, suiteTags)
+	}
+	gauge("xunit.pass_rate", summary.PassRate(), tags)
+
+	if _, err := sendJSON(ctx, "POST", baseUrl+"/api/v2/series", headers, map[string]interface{}{"series": series}, 202); err != nil {
+		return err
+	}
+
+	count := 0
+	for _, testsuite := range testsuites {
+		for _, testcase := range testsuite.Cases {
+			if !testcase.Failed() {
+				continue
+			}
+
+			count++
+			if count > datadogMaxEvents {
+				return nil
+			}
+
+			event := map[string]interface{}{
+				"title":            fmt.Sprintf("Test failed: %s %s", testsuite.Name, testcase.Name),
+				"text":             "%%% \n```\n" + strings.TrimSpace(testcase.Failure.Message) + "\n```\n %%%",
+				"alert_type":       "error",
+				"source_type_name": "xunit-to-github",
+				"tags":             append([]string{"suite:" + testsuite.Name, "classname:" + testcase.Classname}, tags...),
+			}
+			if _, err := sendJSON(ctx, "POST", baseUrl+"/api/v1/events", headers, event, 202); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
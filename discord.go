@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+const discordMaxFailures = 5
+
+func postDiscordMessage(ctx context.Context, webhookUrl string, title string, summary junit.Summary, testsuites []junit.Suite, commentUrl string, jobUrl string) error {
+	if title == "" {
+		title = "Test results"
+	}
+
+	color := 0x2ecc71
+	if summary.Failed() {
+		color = 0xe74c3c
+	}
+
+	fields := []map[string]interface{}{
+		{"name": "Tests", "value": fmt.Sprintf("%d", summary.Tests), "inline": true},
+		{"name": "Failures", "value": fmt.Sprintf("%d", summary.Failures), "inline": true},
+		{"name": "Errors", "value": fmt.Sprintf("%d", summary.Errors), "inline": true},
+		{"name": "Skipped", "value": fmt.Sprintf("%d", summary.Skipped), "inline": true},
+		{"name": "Pass rate", "value": fmt.Sprintf("%.1f%%", summary.PassRate()), "inline": true},
+	}
+
+	var failures []string
+	count := 0
+	for _, testsuite := range testsuites {
+		for _, testcase := range testsuite.Cases {
+			if !testcase.Failed() {
+				continue
+			}
+
+			count++
+			if count <= discordMaxFailures {
+				failures = append(failures, fmt.Sprintf("`%s` %s", testsuite.Name, testcase.Name))
+			}
+		}
+	}
+	if count > discordMaxFailures {
+		failures = append(failures, fmt.Sprintf("and %d more", count-discordMaxFailures))
+	}
+	if len(failures) > 0 {
+		value := strings.Join(failures, "\n")
+		if len(value) > 1024 {
+			value = value[:1021] + "..."
+		}
+		fields = append(fields, map[string]interface{}{"name": "Failing tests", "value": value})
+	}
+
+	embed := map[string]interface{}{
+		"title":  title,
+		"color":  color,
+		"fields": fields,
+	}
+	if commentUrl != "" {
+		embed["url"] = commentUrl
+	} else if jobUrl != "" {
+		embed["url"] = jobUrl
+	}
+	if jobUrl != "" {
+		embed["description"] = fmt.Sprintf("[View job](%s)", jobUrl)
+	}
+
+	message := map[string]interface{}{
+		"embeds": []map[string]interface{}{embed},
+	}
+
+	_, err := sendJSON(ctx, "POST", webhookUrl, nil, message, 0)
+	return err
+}
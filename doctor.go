@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/github"
+)
+
+type doctorOptions struct {
+	Comment *commentOptions
+}
+
+func newDoctorFlags() (*flag.FlagSet, *doctorOptions) {
+	flags := flag.NewFlagSet("xunit-to-github doctor", flag.ExitOnError)
+	options := &doctorOptions{}
+	options.Comment = addCommentFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// slowResponse is how long an api request may take before doctor warns that
+// posting is likely to be slow
+const slowResponse = 2 * time.Second
+
+// rateLimitHeadroom is the fewest requests left in the rate limit before
+// doctor warns that posting may be rate limited
+const rateLimitHeadroom = 100
+
+// doctor prints the result of each preflight check, remembering whether any
+// failed
+type doctor struct {
+	w      io.Writer
+	failed bool
+}
+
+func (d *doctor) ok(check string, format string, args ...interface{}) {
+	fmt.Fprintf(d.w, "ok      %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) warn(check string, format string, args ...interface{}) {
+	fmt.Fprintf(d.w, "warning %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) fail(check string, format string, args ...interface{}) {
+	d.failed = true
+	fmt.Fprintf(d.w, "not ok  %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+// runDoctor checks that a comment can be posted with the current environment
+// and flags, exiting non-zero when it cannot
+func runDoctor(args []string) {
+	flags, options := newDoctorFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	d := &doctor{w: os.Stdout}
+	if options.Comment.Provider == "" {
+		options.Comment.Provider = detectProvider()
+	}
+	if options.Comment.Provider == "github" {
+		checkGithub(ctx, d, options.Comment)
+	} else {
+		d.fail("provider", "only the github provider can be checked, not %s", options.Comment.Provider)
+	}
+
+	if d.failed {
+		exit(exitPublishError)
+	}
+}
+
+// checkGithub checks the token, repository, and pull request that comments
+// are posted with
+func checkGithub(ctx context.Context, d *doctor, options *commentOptions) {
+	accessToken, source, err := githubAccessToken(ctx, options)
+	switch {
+	case err != nil:
+		d.fail("token", "could not read from %s: %s", source, err)
+		return
+	case accessToken != "":
+		d.ok("token", "read from %s", source)
+	case options.GhAuth:
+		d.fail("token", "GITHUB_ACCESS_TOKEN is not set and gh is not logged in, so nothing will be posted")
+		return
+	default:
+		d.fail("token", "GITHUB_ACCESS_TOKEN is not set, so nothing will be posted")
+		return
+	}
+
+	client := &github.Client{HTTPClient: httpClient, BaseURL: os.Getenv("GITHUB_API_URL"), AccessToken: accessToken}
+	start := time.Now()
+	token, err := client.Token(ctx)
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		d.fail("api", "%s", githubProblem(err, "the token is invalid or expired"))
+		return
+	}
+	if latency > slowResponse {
+		d.warn("api", "responded in %s, so posting may time out", latency)
+	} else {
+		d.ok("api", "responded in %s", latency)
+	}
+
+	switch {
+	case token.Scopes == nil:
+		d.ok("scopes", "the token does not report scopes, as with GITHUB_TOKEN and fine-grained tokens")
+	case hasScope(token.Scopes, "repo"):
+		d.ok("scopes", "the token has the repo scope")
+	case hasScope(token.Scopes, "public_repo"):
+		d.warn("scopes", "the token has the public_repo scope, so it can only comment on public repositories")
+	default:
+		d.fail("scopes", "the token needs the repo or public_repo scope to comment, but has %v", token.Scopes)
+	}
+
+	if token.RateLimit > 0 {
+		if token.RateRemaining < rateLimitHeadroom {
+			d.warn("rate limit", "%d of %d requests left until %s", token.RateRemaining, token.RateLimit, token.RateLimitReset.Format(time.RFC3339))
+		} else {
+			d.ok("rate limit", "%d of %d requests left", token.RateRemaining, token.RateLimit)
+		}
+	}
+
+	if options.RepositorySlug == "" {
+		d.fail("repository", "--repository-slug is not set")
+		return
+	}
+	repository, err := client.Repository(ctx, options.RepositorySlug)
+	if err != nil {
+		d.fail("repository", "%s", githubProblem(err, fmt.Sprintf("%s does not exist, or the token cannot access it", options.RepositorySlug)))
+		return
+	}
+	if repository.Archived {
+		d.fail("repository", "%s is archived, so it cannot be commented on", repository.FullName)
+	} else {
+		d.ok("repository", "%s is reachable", repository.FullName)
+	}
+
+	if options.PullRequestId == 0 {
+		d.fail("pull request", "--pull-request-id is not set")
+		return
+	}
+	pullRequest, err := client.PullRequest(ctx, options.RepositorySlug, options.PullRequestId)
+	if err != nil {
+		d.fail("pull request", "%s", githubProblem(err, fmt.Sprintf("pull request #%d does not exist in %s", options.PullRequestId, options.RepositorySlug)))
+		return
+	}
+	switch {
+	case pullRequest.Locked:
+		d.fail("pull request", "#%d is locked, so only collaborators can comment on it", pullRequest.Number)
+	case pullRequest.State != "open":
+		d.fail("pull request", "#%d is %s rather than open", pullRequest.Number, pullRequest.State)
+	default:
+		d.ok("pull request", "#%d is open", pullRequest.Number)
+	}
+}
+
+// githubProblem describes an api error, using notFound for the 404s github
+// also returns when a token cannot see a resource
+func githubProblem(err error, notFound string) string {
+	var apiError *github.Error
+	if !errors.As(err, &apiError) {
+		return fmt.Sprintf("could not reach the api: %s", err)
+	}
+
+	switch apiError.StatusCode {
+	case 401:
+		return "the token is invalid or expired"
+	case 403:
+		return "the token is not allowed to do this, or the rate limit was exceeded"
+	case 404:
+		return notFound
+	}
+	return fmt.Sprintf("the api responded with status %d", apiError.StatusCode)
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
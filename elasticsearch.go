@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func elasticsearchCredentials() string {
+	if apiKey := os.Getenv("ELASTICSEARCH_API_KEY"); apiKey != "" {
+		return "ApiKey " + apiKey
+	}
+
+	username := os.Getenv("ELASTICSEARCH_USERNAME")
+	password := os.Getenv("ELASTICSEARCH_PASSWORD")
+	if username == "" || password == "" {
+		return ""
+	}
+
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func indexTestResults(ctx context.Context, elasticsearchUrl string, index string, run historyRun, testsuites []junit.Suite) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	for _, testsuite := range testsuites {
+		for _, testcase := range testsuite.Cases {
+			encoder.Encode(map[string]interface{}{"index": map[string]string{"_index": index}})
+			encoder.Encode(map[string]interface{}{
+				"@timestamp":   timestamp,
+				"test_id":      fmt.Sprintf("%s/%s.%s", testsuite.Name, testcase.Classname, testcase.Name),
+				"suite":        testsuite.Name,
+				"classname":    testcase.Classname,
+				"name":         testcase.Name,
+				"status":       string(testcase.Status),
+				"duration":     testcase.Time,
+				"message":      testcase.Failure.Message,
+				"repository":   run.Repository,
+				"branch":       run.Branch,
+				"commit":       run.Commit,
+				"pull_request": run.PullRequest,
+			})
+		}
+	}
+
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-ndjson",
+	}
+	if credentials := elasticsearchCredentials(); credentials != "" {
+		headers["Authorization"] = credentials
+	}
+
+	responseBody, err := sendRequest(ctx, "POST", strings.TrimSuffix(elasticsearchUrl, "/")+"/_bulk", headers, buf.Bytes(), 200)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+
+	for _, item := range response.Items {
+		for _, result := range item {
+			if len(result.Error) > 0 {
+				return fmt.Errorf("elasticsearch: %s", string(result.Error))
+			}
+		}
+	}
+	return fmt.Errorf("elasticsearch: bulk request reported errors")
+}
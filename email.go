@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+func sendEmail(ctx context.Context, smtpHost string, username string, from string, recipients []string, title string, summary junit.Summary, testsuites []junit.Suite) error {
+	html, err := render.HTML(title, summary, testsuites)
+	if err != nil {
+		return err
+	}
+
+	if title == "" {
+		title = "Test results"
+	}
+
+	status := "passed"
+	if summary.Failed() {
+		status = "failed"
+	}
+
+	// line breaks in the title would otherwise end the subject header and
+	// start others, and titles that are not ascii must be encoded
+	title = strings.Join(strings.Fields(title), " ")
+	subject := mime.QEncoding.Encode("utf-8", fmt.Sprintf("%s: %s (%d/%d tests passed)", title, status, summary.Passed(), summary.Tests))
+	message := strings.Join([]string{
+		"From: " + from,
+		"To: " + strings.Join(recipients, ", "),
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/html; charset=\"utf-8\"",
+		"",
+		html,
+	}, "\r\n")
+
+	var auth smtp.Auth
+	if username != "" {
+		host, _, err := net.SplitHostPort(smtpHost)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return sendMail(ctx, smtpHost, auth, from, recipients, []byte(message))
+}
+
+// sendMail behaves like smtp.SendMail, but fails once the http timeout elapses
+// or the context is done rather than waiting forever on an unresponsive server
+func sendMail(ctx context.Context, smtpHost string, auth smtp.Auth, from string, recipients []string, message []byte) error {
+	host, _, err := net.SplitHostPort(smtpHost)
+	if err != nil {
+		return err
+	}
+
+	if timeout := httpClient.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", smtpHost)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// close the connection to interrupt the conversation when the context is
+	// cancelled before the deadline
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
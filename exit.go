@@ -0,0 +1,20 @@
+package main
+
+// Exit codes, so that ci scripts can tell what went wrong rather than
+// treating every failure the same
+const (
+	// exitTestsFailed is when tests failed, or required results are missing,
+	// and the run is gating on them
+	exitTestsFailed = 1
+	// exitParseError is when reports or results could not be found or read
+	exitParseError = 2
+	// exitPublishError is when results could not be posted, published, or
+	// written
+	exitPublishError = 3
+	// exitConfigError is when a flag, config file, or environment variable is
+	// invalid
+	exitConfigError = 4
+	// exitInterrupted is when the process received SIGINT or SIGTERM, as
+	// shells report for a process killed by SIGINT
+	exitInterrupted = 130
+)
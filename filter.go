@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+type filterOptions struct {
+	Include  string
+	Excludes stringSlice
+}
+
+func addFilterFlags(flags *flag.FlagSet) *filterOptions {
+	options := &filterOptions{}
+	flags.StringVar(&options.Include, "filter", "", "filter: A regular expression matching the classname.name of tests to include")
+	flags.Var(&options.Excludes, "exclude-tests", "exclude-tests: A regular expression matching the classname.name of tests to exclude")
+	return options
+}
+
+func (o *filterOptions) compile() (junit.Filter, error) {
+	return junit.NewFilter(o.Include, o.Excludes)
+}
+
+// readQuarantine reads the quarantine list at the path, which quarantines
+// nothing when the path is empty
+func readQuarantine(path string) (junit.Quarantine, error) {
+	if path == "" {
+		return junit.Quarantine{}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return junit.Quarantine{}, err
+	}
+	defer file.Close()
+
+	return junit.ReadQuarantine(file)
+}
+
+// readOwners reads the owners file at the path, which assigns no owners when
+// the path is empty
+func readOwners(path string) (junit.Owners, error) {
+	if path == "" {
+		return junit.Owners{}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return junit.Owners{}, err
+	}
+	defer file.Close()
+
+	return junit.ReadOwners(file)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+var thresholdFlags = map[string]bool{
+	"min-pass-rate": true,
+	"max-failures":  true,
+	"min-tests":     true,
+}
+
+// Thresholds decide whether a run passes, which determines both check
+// conclusions and, when gating, the exit code. Each threshold only applies
+// when its flag was specified, and a run with failed tests does not pass when
+// none were, or when FailOnFailure is set and --max-failures was not.
+type Thresholds struct {
+	MinPassRate   float64
+	MaxFailures   int
+	MinTests      int
+	FailOnFailure bool
+	flags         *flag.FlagSet
+}
+
+func addThresholdFlags(flags *flag.FlagSet) *Thresholds {
+	thresholds := &Thresholds{flags: flags}
+	flags.Float64Var(&thresholds.MinPassRate, "min-pass-rate", 0, "min-pass-rate: The minimum percentage of executed tests that must pass")
+	flags.IntVar(&thresholds.MaxFailures, "max-failures", 0, "max-failures: The maximum number of failed and errored tests to tolerate")
+	flags.IntVar(&thresholds.MinTests, "min-tests", 0, "min-tests: The minimum number of tests that must be run")
+	return thresholds
+}
+
+// gating returns whether any threshold was configured, in which case the
+// thresholds determine the exit code
+func gating(flags *flag.FlagSet) bool {
+	enabled := false
+	flags.Visit(func(f *flag.Flag) {
+		if thresholdFlags[f.Name] {
+			enabled = true
+		}
+	})
+	return enabled
+}
+
+// configured returns the threshold flags that were specified, along with
+// max-failures when none were or when failing on any failure
+func (t Thresholds) configured() map[string]bool {
+	configured := map[string]bool{}
+	if t.flags != nil {
+		t.flags.Visit(func(f *flag.Flag) {
+			if thresholdFlags[f.Name] {
+				configured[f.Name] = true
+			}
+		})
+	}
+	if len(configured) == 0 || t.FailOnFailure {
+		configured["max-failures"] = true
+	}
+	return configured
+}
+
+func (t Thresholds) Violations(summary junit.Summary) []string {
+	var violations []string
+	configured := t.configured()
+	if failures := summary.Failures + summary.Errors; configured["max-failures"] && failures > t.MaxFailures {
+		violations = append(violations, fmt.Sprintf("%d failed tests exceed the maximum of %d", failures, t.MaxFailures))
+	}
+	if configured["min-pass-rate"] && summary.PassRate() < t.MinPassRate {
+		violations = append(violations, fmt.Sprintf("pass rate of %.2f%% is below the minimum of %.2f%%", summary.PassRate(), t.MinPassRate))
+	}
+	if configured["min-tests"] && summary.Tests < t.MinTests {
+		violations = append(violations, fmt.Sprintf("%d tests is below the minimum of %d", summary.Tests, t.MinTests))
+	}
+	return violations
+}
+
+func (t Thresholds) Passed(summary junit.Summary) bool {
+	return len(t.Violations(summary)) == 0
+}
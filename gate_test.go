@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func TestThresholdsPassed(t *testing.T) {
+	failing := junit.Summary{Tests: 10, Failures: 1}
+	passing := junit.Summary{Tests: 10}
+	tests := []struct {
+		name          string
+		args          []string
+		failOnFailure bool
+		summary       junit.Summary
+		passed        bool
+	}{
+		{"no thresholds with a failure", nil, false, failing, false},
+		{"no thresholds without failures", nil, false, passing, true},
+		{"min-tests alone ignores failures", []string{"--min-tests", "1"}, false, failing, true},
+		{"min-pass-rate alone ignores failures", []string{"--min-pass-rate", "80"}, false, failing, true},
+		{"min-pass-rate alone fails below the rate", []string{"--min-pass-rate", "95"}, false, failing, false},
+		{"max-failures tolerates failures", []string{"--max-failures", "1"}, false, failing, true},
+		{"fail-on-failure with a failure", nil, true, failing, false},
+		{"fail-on-failure with min-tests", []string{"--min-tests", "1"}, true, failing, false},
+		{"fail-on-failure with min-pass-rate", []string{"--min-pass-rate", "80"}, true, failing, false},
+		{"fail-on-failure with min-tests and no failures", []string{"--min-tests", "1"}, true, passing, true},
+		{"fail-on-failure with too few tests", []string{"--min-tests", "20"}, true, passing, false},
+		{"fail-on-failure with max-failures", []string{"--max-failures", "1", "--min-tests", "1"}, true, failing, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			thresholds := addThresholdFlags(flags)
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			thresholds.FailOnFailure = test.failOnFailure
+
+			if passed := thresholds.Passed(test.summary); passed != test.passed {
+				t.Errorf("Passed() = %v, want %v, with violations %v", passed, test.passed, thresholds.Violations(test.summary))
+			}
+		})
+	}
+}
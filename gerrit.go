@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func gerritCredentials() string {
+	username := os.Getenv("GERRIT_USERNAME")
+	password := os.Getenv("GERRIT_PASSWORD")
+	if username == "" || password == "" {
+		return ""
+	}
+
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func postGerritReview(ctx context.Context, gerritUrl string, changeNumber int, revision string, credentials string, label string, passed bool, body string) error {
+	if revision == "" {
+		revision = "current"
+	}
+
+	url := fmt.Sprintf("%s/a/changes/%d/revisions/%s/review", strings.TrimSuffix(gerritUrl, "/"), changeNumber, revision)
+	headers := map[string]string{
+		"Authorization": credentials,
+	}
+
+	review := map[string]interface{}{
+		"message": body,
+	}
+
+	if label != "" {
+		vote := 1
+		if !passed {
+			vote = -1
+		}
+
+		review["labels"] = map[string]int{
+			label: vote,
+		}
+	}
+
+	_, err := sendJSON(ctx, "POST", url, headers, review, 200)
+	return err
+}
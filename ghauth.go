@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// githubHost is the host gh stores credentials under for an api url, which is
+// github.com unless the url points at a github enterprise server
+func githubHost(apiUrl string) string {
+	if apiUrl == "" {
+		return "github.com"
+	}
+	parsed, err := url.Parse(apiUrl)
+	if err != nil || parsed.Hostname() == "" || parsed.Hostname() == "api.github.com" {
+		return "github.com"
+	}
+	return parsed.Hostname()
+}
+
+// ghAuthToken asks the gh cli for the token it is logged in to the host with,
+// returning nothing when gh is not installed or not logged in
+func ghAuthToken(ctx context.Context, host string) string {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", host)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		logger.Debug("could not read gh auth token", "host", host, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(stdout.String())
+}
+
+// ghConfigDir is where the gh cli keeps its configuration
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh")
+}
+
+// ghHostsToken reads the oauth token for the host from the hosts.yml file
+// written by versions of the gh cli that lack `gh auth token`, or that store
+// tokens in plain text rather than the system keyring
+func ghHostsToken(dir string, host string) string {
+	if dir == "" {
+		return ""
+	}
+	file, err := os.Open(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inHost := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			inHost = strings.Trim(strings.TrimSuffix(line, ":"), `"'`) == host
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if inHost && len(parts) == 2 && parts[0] == "oauth_token" {
+			return strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type giteaComment struct {
+	Id      int64  `json:"id"`
+	HtmlUrl string `json:"html_url"`
+}
+
+// postGiteaComment posts the body as a comment on the pull request, or edits
+// the existing comment when commentId is set
+func postGiteaComment(ctx context.Context, giteaUrl string, repositorySlug string, pullRequestId int, accessToken string, commentId int64, body string) (giteaComment, error) {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/issues/%d/comments", strings.TrimSuffix(giteaUrl, "/"), repositorySlug, pullRequestId)
+	headers := map[string]string{
+		"Authorization": "token " + accessToken,
+	}
+
+	message := map[string]interface{}{
+		"body": body,
+	}
+
+	var comment giteaComment
+	method, expectedStatus := "POST", 201
+	if commentId != 0 {
+		url = fmt.Sprintf("%s/api/v1/repos/%s/issues/comments/%d", strings.TrimSuffix(giteaUrl, "/"), repositorySlug, commentId)
+		method, expectedStatus = "PATCH", 200
+	}
+
+	responseBody, err := sendJSON(ctx, method, url, headers, message, expectedStatus)
+	if err != nil {
+		return comment, err
+	}
+
+	err = json.Unmarshal(responseBody, &comment)
+	return comment, err
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+func postGitlabComment(ctx context.Context, gitlabUrl string, projectId string, mergeRequestIid int, accessToken string, body string) error {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/notes", strings.TrimSuffix(gitlabUrl, "/"), url.PathEscape(projectId), mergeRequestIid)
+	headers := map[string]string{
+		"PRIVATE-TOKEN": accessToken,
+	}
+
+	message := map[string]interface{}{
+		"body": body,
+	}
+
+	_, err := sendJSON(ctx, "POST", endpoint, headers, message, 201)
+	return err
+}
@@ -1,3 +1,3 @@
-module xunit-to-github
+module github.com/josegonzalez/go-xunit-to-github
 
-go 1.12
+go 1.16
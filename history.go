@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+var historySchema = []string{
+	`CREATE TABLE IF NOT EXISTS runs (id INTEGER PRIMARY KEY AUTOINCREMENT, created_at TEXT NOT NULL, repository TEXT, branch TEXT, commit_sha TEXT, pull_request INTEGER, tests INTEGER, failures INTEGER, errors INTEGER, skipped INTEGER)`,
+	`CREATE TABLE IF NOT EXISTS results (run_id INTEGER NOT NULL REFERENCES runs(id), suite TEXT, classname TEXT, name TEXT, status TEXT, duration REAL, message TEXT)`,
+	`CREATE INDEX IF NOT EXISTS results_test ON results (suite, classname, name)`,
+}
+
+// historyDB stores runs in a local sqlite database through the sqlite3 cli,
+// or in a remote rqlite database when the location is an http(s) url
+type historyDB struct {
+	location string
+}
+
+type historyRun struct {
+	Repository  string
+	Branch      string
+	Commit      string
+	PullRequest int
+}
+
+func (h historyDB) remote() bool {
+	return strings.HasPrefix(h.location, "http://") || strings.HasPrefix(h.location, "https://")
+}
+
+func (h historyDB) exec(ctx context.Context, statements []string) error {
+	if h.remote() {
+		_, err := h.execRemote(ctx, statements)
+		return err
+	}
+
+	script := "BEGIN;\n" + strings.Join(statements, ";\n") + ";\nCOMMIT;\n"
+	cmd := exec.CommandContext(ctx, "sqlite3", "-bail", h.location)
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sqlite3: %s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// execRemote runs the statements on rqlite in a transaction, returning the id
+// of the row inserted by the last of them
+func (h historyDB) execRemote(ctx context.Context, statements []string) (int64, error) {
+	responseBody, err := sendJSON(ctx, "POST", strings.TrimSuffix(h.location, "/")+"/db/execute?transaction", nil, statements, 200)
+	if err != nil {
+		return 0, err
+	}
+	if err := rqliteError(responseBody); err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		Results []struct {
+			LastInsertId int64 `json:"last_insert_id"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return 0, err
+	}
+	if len(response.Results) == 0 {
+		return 0, nil
+	}
+	return response.Results[len(response.Results)-1].LastInsertId, nil
+}
+
+func (h historyDB) query(ctx context.Context, statement string) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if h.remote() {
+		responseBody, err := sendJSON(ctx, "POST", strings.TrimSuffix(h.location, "/")+"/db/query?associative", nil, []string{statement}, 200)
+		if err != nil {
+			return rows, err
+		}
+		if err := rqliteError(responseBody); err != nil {
+			return rows, err
+		}
+
+		var response struct {
+			Results []struct {
+				Rows []map[string]interface{} `json:"rows"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(responseBody, &response); err != nil {
+			return rows, err
+		}
+		if len(response.Results) > 0 {
+			rows = response.Results[0].Rows
+		}
+		return rows, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sqlite3", "-json", h.location, statement)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return rows, fmt.Errorf("sqlite3: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return rows, nil
+	}
+
+	err = json.Unmarshal(output, &rows)
+	return rows, err
+}
+
+func rqliteError(responseBody []byte) error {
+	var response struct {
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return err
+	}
+	for _, result := range response.Results {
+		if result.Error != "" {
+			return fmt.Errorf("rqlite: %s", result.Error)
+		}
+	}
+	return nil
+}
+
+// recordRun inserts the run and the results of its testcases. The results
+// refer to the id the run was inserted with, which rqlite returns, and which
+// sqlite keeps in a temporary table for the rest of the script, so that runs
+// recorded at the same time do not get each other's results.
+func (h historyDB) recordRun(ctx context.Context, run historyRun, summary junit.Summary, testsuites []junit.Suite) error {
+	statements := append([]string{}, historySchema...)
+	statements = append(statements, fmt.Sprintf(
+		"INSERT INTO runs (created_at, repository, branch, commit_sha, pull_request, tests, failures, errors, skipped) VALUES (%s, %s, %s, %s, %d, %d, %d, %d, %d)",
+		sqlQuote(time.Now().UTC().Format(time.RFC3339)), sqlQuote(run.Repository), sqlQuote(run.Branch), sqlQuote(run.Commit), run.PullRequest,
+		summary.Tests, summary.Failures, summary.Errors, summary.Skipped,
+	))
+
+	runId := "(SELECT id FROM current_run)"
+	if h.remote() {
+		id, err := h.execRemote(ctx, statements)
+		if err != nil {
+			return err
+		}
+		statements, runId = nil, strconv.FormatInt(id, 10)
+	} else {
+		statements = append(statements, "CREATE TEMP TABLE current_run AS SELECT last_insert_rowid() AS id")
+	}
+
+	for _, testsuite := range testsuites {
+		for _, testcase := range testsuite.Cases {
+			statements = append(statements, fmt.Sprintf(
+				"INSERT INTO results (run_id, suite, classname, name, status, duration, message) VALUES (%s, %s, %s, %s, %s, %g, %s)",
+				runId, sqlQuote(testsuite.Name), sqlQuote(testcase.Classname), sqlQuote(testcase.Name), sqlQuote(string(testcase.Status)), testcase.Time, sqlQuote(testcase.Failure.Message),
+			))
+		}
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	return h.exec(ctx, statements)
+}
+
+// recentHistory counts how often each testcase failed over the runs within
+// the window, keyed by testcase id, including the current run which has not
+// yet been recorded
+func (h historyDB) recentHistory(ctx context.Context, repository string, window int, report junit.Report) (map[string]junit.History, error) {
+	histories := map[string]junit.History{}
+	if window < 1 {
+		return histories, fmt.Errorf("history window must be at least 1, got %d", window)
+	}
+	if err := h.exec(ctx, historySchema); err != nil {
+		return histories, err
+	}
+
+	runs := "SELECT id FROM runs"
+	if repository != "" {
+		runs += " WHERE repository = " + sqlQuote(repository)
+	}
+	runs += fmt.Sprintf(" ORDER BY id DESC LIMIT %d", window-1)
+
+	rows, err := h.query(ctx, "SELECT classname, name, count(*) AS runs, sum(CASE WHEN status IN ('failed', 'error') THEN 1 ELSE 0 END) AS failures FROM results WHERE run_id IN ("+runs+") GROUP BY classname, name")
+	if err != nil {
+		return histories, err
+	}
+
+	previous := map[string]junit.History{}
+	for _, row := range rows {
+		testcase := junit.Case{Classname: fmt.Sprint(row["classname"]), Name: fmt.Sprint(row["name"])}
+		previous[testcase.Id()] = junit.History{Failures: rowInt(row["failures"]), Runs: rowInt(row["runs"])}
+	}
+
+	for _, testsuite := range report.Suites {
+		for _, testcase := range testsuite.Cases {
+			history := previous[testcase.Id()]
+			history.Runs++
+			if testcase.Failed() {
+				history.Failures++
+			}
+			histories[testcase.Id()] = history
+		}
+	}
+	return histories, nil
+}
+
+// rowInt reads a number from a row, which is decoded from json
+func rowInt(value interface{}) int {
+	if number, ok := value.(float64); ok {
+		return int(number)
+	}
+	return 0
+}
+
+// annotateHistory adds how often each failing testcase failed recently to
+// the report, for testcases that have been recorded before, leaving the report
+// as it is when the history cannot be read
+func annotateHistory(ctx context.Context, db historyDB, repository string, window int, report junit.Report) junit.Report {
+	histories, err := db.recentHistory(ctx, repository, window, report)
+	if err != nil {
+		logger.Warn("could not read run history", "error", err)
+		return report
+	}
+
+	suites := make([]junit.Suite, len(report.Suites))
+	for i, testsuite := range report.Suites {
+		cases := make([]junit.Case, len(testsuite.Cases))
+		for j, testcase := range testsuite.Cases {
+			if history := histories[testcase.Id()]; history.Runs > 1 && testcase.Failed() {
+				testcase.History = &history
+			}
+			cases[j] = testcase
+		}
+		testsuite.Cases = cases
+		suites[i] = testsuite
+	}
+	report.Suites = suites
+	return report
+}
+
+func sqlQuote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var logLevelNames = map[logLevel]string{
+	levelDebug: "debug",
+	levelInfo:  "info",
+	levelWarn:  "warn",
+	levelError: "error",
+}
+
+// Logger writes leveled diagnostics as logfmt or json lines
+type Logger struct {
+	mu       sync.Mutex
+	level    logLevel
+	json     bool
+	output   io.Writer
+	terminal bool
+	progress bool
+}
+
+var logger = &Logger{level: levelInfo, output: os.Stderr, terminal: isTerminal(os.Stderr)}
+
+// progressMinimum is the fewest items worth reporting progress for
+const progressMinimum = 50
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (l *Logger) Configure(level string, format string) error {
+	found := false
+	for value, name := range logLevelNames {
+		if name == strings.ToLower(level) {
+			l.level = value
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+
+	switch format {
+	case "text":
+		l.json = false
+	case "json":
+		l.json = true
+	default:
+		return fmt.Errorf("invalid log format: %s", format)
+	}
+
+	return nil
+}
+
+// Quiet raises the default info level so only errors are logged
+func (l *Logger) Quiet() {
+	if l.level == levelInfo {
+		l.level = levelError
+	}
+}
+
+func (l *Logger) log(level logLevel, msg string, keyvals []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.progress {
+		fmt.Fprint(l.output, "\r\x1b[K")
+		l.progress = false
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	if l.json {
+		entry := map[string]interface{}{"time": now, "level": logLevelNames[level], "msg": msg}
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			value := keyvals[i+1]
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			entry[fmt.Sprintf("%v", keyvals[i])] = value
+		}
+		data, _ := json.Marshal(entry)
+		fmt.Fprintln(l.output, string(data))
+		return
+	}
+
+	line := fmt.Sprintf("time=%s level=%s msg=%s", now, logLevelNames[level], logfmtValue(msg))
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		line += fmt.Sprintf(" %v=%s", keyvals[i], logfmtValue(fmt.Sprintf("%v", keyvals[i+1])))
+	}
+	fmt.Fprintln(l.output, line)
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \"=\n\t") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(levelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(levelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(levelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(levelError, msg, keyvals)
+}
+
+// Progress reports that done of total items have been processed, redrawing a
+// single status line when logging text to a terminal, and otherwise logging
+// every tenth of the way. Nothing is reported for fewer than progressMinimum
+// items, or when only warnings and errors are logged.
+func (l *Logger) Progress(msg string, done int, total int, item string) {
+	if total < progressMinimum || l.level > levelInfo {
+		return
+	}
+
+	if !l.terminal || l.json {
+		if done == total || done*10/total != (done-1)*10/total {
+			l.Info(msg, "done", done, "total", total)
+		}
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if done == total {
+		fmt.Fprint(l.output, "\r\x1b[K")
+		l.progress = false
+		return
+	}
+
+	if len(item) > 60 {
+		item = "..." + item[len(item)-57:]
+	}
+	fmt.Fprintf(l.output, "\r\x1b[K%s %d/%d %s", msg, done, total, item)
+	l.progress = true
+}
+
+// Fatal logs at the error level and exits with the code
+func (l *Logger) Fatal(code int, msg string, keyvals ...interface{}) {
+	l.log(levelError, msg, keyvals)
+	exit(code)
+}
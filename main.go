@@ -2,213 +2,426 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"encoding/xml"
+	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
 )
 
-type Testsuite struct {
-	XMLName   xml.Name   `xml:"testsuite"`
-	Testcases []Testcase `xml:"testcase"`
-	Name      string     `xml:"name,attr"`
-	Tests     int        `xml:"tests,attr"`
-	Failures  int        `xml:"failures,attr"`
-	Errors    int        `xml:"errors,attr"`
-	Skipped   int        `xml:"skipped,attr"`
-	Time      string     `xml:"time,attr"`
-	Timestamp string     `xml:"timestamp,attr"`
-	Hostname  string     `xml:"hostname,attr"`
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ",")
 }
 
-type Testcase struct {
-	XMLName   xml.Name `xml:"testcase"`
-	Classname string   `xml:"classname,attr"`
-	Name      string   `xml:"name,attr"`
-	Time      int      `xml:"time,attr"`
-	Failure   Failure  `xml:"failure"`
+func (s *stringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
 }
 
-type Failure struct {
-	XMLName xml.Name `xml:"failure"`
-	Type    string   `xml:"type,attr"`
-	Message string   `xml:",chardata"`
+// pathMappings is a flag for mappings of classname prefixes to the
+// directories of the repository their files are in, such as
+// com.example.api=api/src/test/java/com/example/api
+type pathMappings []junit.PathMapping
+
+func (m *pathMappings) String() string {
+	var pairs []string
+	for _, mapping := range *m {
+		pairs = append(pairs, mapping.Prefix+"="+mapping.Path)
+	}
+	return strings.Join(pairs, ",")
 }
 
-func getFiles(args []string) ([]string, error) {
-	if len(args) == 0 {
-		return getFilesFromPath("./")
+func (m *pathMappings) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return fmt.Errorf("invalid path mapping %s, expected prefix=path", value)
 	}
+	directory := path.Clean(strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(parts[1])), "./"))
+	if !fs.ValidPath(directory) {
+		return fmt.Errorf("invalid path mapping %s, expected a path relative to the repository", value)
+	}
+	*m = append(*m, junit.PathMapping{Prefix: strings.TrimSpace(parts[0]), Path: directory})
+	return nil
+}
 
-	var files []string
-	for _, arg := range args {
-		f, err := os.Stat(arg)
-		if err != nil {
-			return files, err
+// reproduceTemplates is a flag for the command templates of frameworks, such
+// as java=./gradlew test --tests {{ .Class }}.{{ .Method }}
+type reproduceTemplates map[string]string
+
+func (r *reproduceTemplates) String() string {
+	return mappingString(*r)
+}
+
+func (r *reproduceTemplates) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return fmt.Errorf("invalid reproduce template %s, expected framework=template", value)
+	}
+	framework := strings.TrimSpace(parts[0])
+	if !contains(junit.ReproduceFrameworks(), framework) {
+		return fmt.Errorf("unknown %s, expected one of %s", framework, strings.Join(junit.ReproduceFrameworks(), ", "))
+	}
+	if *r == nil {
+		*r = reproduceTemplates{}
+	}
+	(*r)[framework] = parts[1]
+	return nil
+}
+
+// byteSize is a flag for a number of bytes, with an optional KB, MB, or GB
+// suffix
+type byteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+func (b *byteSize) String() string {
+	for _, unit := range byteSizeUnits {
+		if *b != 0 && int64(*b)%unit.size == 0 {
+			return fmt.Sprintf("%d%s", int64(*b)/unit.size, unit.suffix)
 		}
-		if f.IsDir() {
-			filesInPath, err := getFilesFromPath(arg)
-			if err != nil {
-				return files, err
-			}
+	}
+	return "0"
+}
 
-			for _, file := range filesInPath {
-				if filepath.Ext(file) == ".xml" {
-					files = append(files, file)
-				}
-			}
-		} else {
-			if filepath.Ext(f.Name()) == ".xml" {
-				files = append(files, f.Name())
+func (b *byteSize) Set(value string) error {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid size: %s", value)
 			}
+			*b = byteSize(number * unit.size)
+			return nil
 		}
 	}
 
-	return files, nil
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size: %s", value)
+	}
+	*b = byteSize(number)
+	return nil
 }
 
-func getFilesFromPath(path string) ([]string, error) {
-	path = strings.TrimSuffix(path, "/")
-	var files []string
-	filePaths, err := ioutil.ReadDir(path)
+// labelSet is a flag for labels written as key=value pairs, such as
+// os=linux,go=1.22, which may be given more than once
+type labelSet map[string]string
+
+func (l *labelSet) String() string {
+	return junit.Suite{Labels: *l}.Label()
+}
+
+func (l *labelSet) Set(value string) error {
+	labels, err := junit.ParseLabels(value)
 	if err != nil {
-		return files, err
+		return err
+	}
+	if *l == nil {
+		*l = labelSet{}
+	}
+	for key, value := range labels {
+		(*l)[key] = value
 	}
+	return nil
+}
 
-	for _, f := range filePaths {
-		if f.IsDir() {
-			continue
-		}
-		if filepath.Ext(f.Name()) == ".xml" {
-			files = append(files, fmt.Sprintf("%s/%s", path, f.Name()))
+// sinceTime is a flag for a point in time, given as a timestamp, a duration
+// before now, or a file whose modification time is used
+type sinceTime struct {
+	value string
+	time  time.Time
+}
+
+var sinceLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func (s *sinceTime) String() string {
+	return s.value
+}
+
+func (s *sinceTime) Set(value string) error {
+	value = strings.TrimSpace(value)
+	s.value = value
+	if value == "" {
+		s.time = time.Time{}
+		return nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		s.time = time.Now().Add(-duration)
+		return nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			s.time = t
+			return nil
 		}
 	}
+	if info, err := os.Stat(value); err == nil {
+		s.time = info.ModTime()
+		return nil
+	}
+	return fmt.Errorf("invalid time, duration, or file: %s", value)
+}
 
-	return files, nil
+type parsingOptions struct {
+	Concurrency   int
+	MaxReportSize byteSize
+	Lenient       bool
+	IgnoreMissing bool
+	Redact        stringSlice
+	Since         sinceTime
+	Labels        labelSet
+	MergeShards   bool
+	SourceRoot    string
+	SourceUrl     string
+	PathMappings  pathMappings
+	FullTraces    bool
+	SlowThreshold time.Duration
+	Names         junit.NameOptions
 }
 
-func processFile(file string, skipOk bool) (string, error) {
-	body := ""
+func addParsingFlags(flags *flag.FlagSet) *parsingOptions {
+	options := &parsingOptions{MaxReportSize: 512 << 20}
+	flags.IntVar(&options.Concurrency, "concurrency", runtime.NumCPU(), "concurrency: How many reports to parse at a time")
+	flags.Var(&options.MaxReportSize, "max-report-size", "max-report-size: The most of each report to read, such as 512MB, or 0 for no limit")
+	flags.BoolVar(&options.IgnoreMissing, "ignore-missing", false, "ignore-missing: Whether to warn about report paths that do not exist rather than fail, such as when an optional test stage did not run")
+	flags.Var(&options.Redact, "redact", "redact: A regular expression matching secrets to redact from failure output, along with common credentials such as aws keys and bearer tokens")
+	flags.BoolVar(&options.MergeShards, "merge-shards", true, "merge-shards: Whether to combine suites with the same name and different testcases, such as a suite split across shards, into one suite")
+	flags.Var(&options.Labels, "label", "label: Labels for the matrix build the reports are from, such as os=linux,go=1.22, so that results from several builds can be combined into a matrix")
+	flags.Var(&options.Since, "since", "since: Only read reports modified after a time, such as 2024-01-02T15:04:05Z, a duration before now such as 1h, or a file whose modification time is used")
+	flags.StringVar(&options.SourceRoot, "source-root", "", "source-root: The directory the repository was checked out to, which stack traces are made relative to, defaulting to the checkout directory of the ci provider or the current directory")
+	flags.StringVar(&options.SourceUrl, "source-url", "", "source-url: A url that files in the repository can be viewed under, such as https://github.com/owner/repo/blob/<commit>, defaulting to the commit when run on github actions or gitlab")
+	flags.Var(&options.PathMappings, "path-map", "path-map: A classname prefix and the directory of the repository its files are in, such as com.example.api=api/src/test/java/com/example/api, for layouts the built-in heuristics do not find")
+	flags.BoolVar(&options.FullTraces, "full-stack-traces", false, "full-stack-traces: Whether to show every frame of stack traces, rather than folding the frames of test frameworks, runtimes, and dependencies")
+	flags.DurationVar(&options.SlowThreshold, "slow-threshold", 0, "slow-threshold: How long a test may take before it is marked as slow and listed separately, such as 5s, or 0 to mark none")
+	flags.Var((*stringSlice)(&options.Names.StripPrefixes), "strip-prefix", "strip-prefix: A prefix to leave out of rendered test names and classnames, such as com.example.")
+	flags.BoolVar(&options.Names.StripPackages, "strip-packages", false, "strip-packages: Whether to render classnames without their package, such as LoginTest for com.example.LoginTest")
+	flags.BoolVar(&options.Names.StripTestPrefix, "strip-test-prefix", false, "strip-test-prefix: Whether to render test names without a leading Test or test_")
+	flags.BoolVar(&options.Names.Humanize, "humanize-names", false, "humanize-names: Whether to render camel case and snake case test names as words, such as user can log in for test_user_can_log_in")
+	flags.IntVar(&options.Names.MaxParameters, "trim-parameters", 0, "trim-parameters: The most characters of the parameters of parameterized test names to render, or 0 to render them in full")
+	flags.BoolVar(&options.Lenient, "lenient", false, "lenient: Whether to recover from invalid characters, bare ampersands, and undeclared entities in reports")
+	return options
+}
 
-	xmlFile, err := os.Open(file)
-	if err != nil {
-		return body, err
+// findFiles finds the reports among the paths like junit.GetFiles, leaving
+// out those modified before Since. When IgnoreMissing is set, paths that do
+// not exist are warned about and returned separately rather than failing, and
+// finding none of the paths finds no reports rather than defaulting to the
+// current directory.
+func (o *parsingOptions) findFiles(paths []string) ([]string, []string, error) {
+	if !o.IgnoreMissing {
+		files, err := junit.GetFiles(paths)
+		return o.modifiedSince(files), nil, err
 	}
 
-	defer xmlFile.Close()
+	existing, missing := existingPaths(paths)
+	for _, path := range missing {
+		logger.Warn("no reports found", "path", path)
+	}
+	if len(paths) > 0 && len(existing) == 0 {
+		return nil, missing, nil
+	}
+	files, err := junit.GetFiles(existing)
+	return o.modifiedSince(files), missing, err
+}
 
-	byteValue, _ := ioutil.ReadAll(xmlFile)
-	var testsuite Testsuite
-	xml.Unmarshal(byteValue, &testsuite)
+// modifiedSince leaves out the files modified before Since, such as stale
+// reports left on a persistent runner by earlier builds
+func (o *parsingOptions) modifiedSince(files []string) []string {
+	if o.Since.time.IsZero() {
+		return files
+	}
 
-	if !skipOk || testsuite.Failures > 0 {
-		message := fmt.Sprintf("1..%d (%s)", testsuite.Tests, testsuite.Name)
-		body += "### " + message + "\n\n"
-		println(message)
+	var recent []string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err == nil && info.ModTime().Before(o.Since.time) {
+			logger.Debug("skipping stale report", "file", file, "modified", info.ModTime())
+			continue
+		}
+		recent = append(recent, file)
 	}
+	return recent
+}
 
-	for i, testcase := range testsuite.Testcases {
-		if len(testcase.Failure.Message) == 0 {
-			if !skipOk {
-				message := fmt.Sprintf("ok %d %s in %dsec", i, testcase.Name, testcase.Time)
-				body += "<details><summary>" + message + "</summary></details>\n"
-				println(message)
-			}
-		} else {
-			message := fmt.Sprintf("not ok %d %s in %dsec", i, testcase.Name, testcase.Time)
-			body += "<details><summary>" + message + "</summary>\n"
-			println(message)
-			lines := strings.Split("\n"+strings.TrimSpace(testcase.Failure.Message)+"\n", "\n")
-			for _, line := range lines {
-				message := fmt.Sprintf("    %v", line)
-				body += message + "\n"
-				println(message)
+// existingPaths splits the paths into those that exist, or are glob patterns
+// that match something, and those that do not
+func existingPaths(paths []string) ([]string, []string) {
+	var existing, missing []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if matches, _ := filepath.Glob(path); len(matches) == 0 {
+				missing = append(missing, path)
+				continue
 			}
-			body += "</details>\n"
 		}
+		existing = append(existing, path)
 	}
-
-	return body, nil
+	return existing, missing
 }
 
-func main() {
-	flags := flag.NewFlagSet("xunit-to-github", flag.ExitOnError)
-	skipOk := flags.Bool("skip-ok", false, "skip-ok: Whether to skip ok tests or not")
-	title := flags.String("title", "", "title: A title for the comment")
-	jobUrl := flags.String("job-url", "", "job-url: A url for the report")
-	pullRequestId := flags.Int("pull-request-id", 0, "pull-request-id: A pull request ID")
-	repositorySlug := flags.String("repository-slug", "", "repository-slug: The slug of the repository")
-	flags.Parse(os.Args[1:])
-	args := flags.Args()
-
-	files, err := getFiles(args)
+// parseFiles parses the files with a junit.Parser, logging progress, how
+// long each file took to parse, and the files that could not be parsed, then
+// redacts secrets from the results, locates the failures within the
+// repository, folds the framework frames of their stack traces, labels them,
+// merges the suites of shards, marks the slow testcases, and prettifies the
+// names they are rendered with
+func (o *parsingOptions) parseFiles(ctx context.Context, files []string) (junit.Report, error) {
+	redactor, err := junit.NewRedactor(o.Redact)
 	if err != nil {
-		log.Fatal(err)
+		return junit.Report{}, err
 	}
 
-	body := ""
-	for _, file := range files {
-		data, err := processFile(file, *skipOk)
+	parser := junit.Parser{
+		Concurrency: o.Concurrency,
+		MaxSize:     int64(o.MaxReportSize),
+		Lenient:     o.Lenient,
+		Progress: func(file string, duration time.Duration, done int) {
+			logger.Debug("parsed report", "file", file, "duration", duration)
+			logger.Progress("parsing reports", done, len(files), file)
+		},
+	}
+	report, err := parser.ParseFiles(ctx, files)
+	report = junit.RedactReport(report, redactor)
+	root := o.sourceRoot()
+	locator := &junit.Locator{Root: root, SourceUrl: o.sourceUrl(), Paths: o.PathMappings}
+	if info, err := os.Stat(root); err == nil && info.IsDir() {
+		locator.FS = os.DirFS(root)
+	}
+	report = junit.LocateFailures(report, locator)
+	if !o.FullTraces {
+		report = junit.FoldReport(report, root)
+	}
+	report = junit.LabelReport(report, o.Labels)
+	if o.MergeShards {
+		report = junit.MergeShards(report)
+	}
+	report = junit.MarkSlow(report, o.SlowThreshold)
+	report = junit.PrettifyNames(report, o.Names)
+	for _, parseError := range report.ParseErrors {
+		logger.Warn("could not parse report", "file", parseError.File, "error", parseError.Message)
+	}
+	return report, err
+}
+
+// sourceRoot is the directory stack traces are made relative to, which is
+// the checkout directory of the ci provider unless SourceRoot is set
+func (o *parsingOptions) sourceRoot() string {
+	if o.SourceRoot != "" {
+		root, err := filepath.Abs(o.SourceRoot)
 		if err != nil {
-			log.Fatal(err)
+			return o.SourceRoot
 		}
-		body += data + "\n"
+		return root
 	}
-
-	githubAccessToken := os.Getenv("GITHUB_ACCESS_TOKEN")
-	if githubAccessToken == "" {
-		return
+	for _, key := range []string{"GITHUB_WORKSPACE", "CI_PROJECT_DIR", "BUILD_SOURCESDIRECTORY", "BITBUCKET_CLONE_DIR", "BUILDKITE_BUILD_CHECKOUT_PATH", "WORKSPACE"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
 	}
+	root, _ := os.Getwd()
+	return root
+}
 
-	if *pullRequestId == 0 || *repositorySlug == "" {
-		return
+// sourceUrl is the url that files in the repository are viewed under, which
+// is the commit on github or gitlab unless SourceUrl is set
+func (o *parsingOptions) sourceUrl() string {
+	if o.SourceUrl != "" {
+		return o.SourceUrl
 	}
-
-	if body == "" {
-		return
+	if server, repository, commit := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_SHA"); server != "" && repository != "" && commit != "" {
+		return server + "/" + repository + "/blob/" + commit
 	}
-
-	if *jobUrl != "" {
-		body = fmt.Sprintf("[Build Url](%s)", *jobUrl) + "\n\n" + body
+	if project, commit := os.Getenv("CI_PROJECT_URL"), os.Getenv("CI_COMMIT_SHA"); project != "" && commit != "" {
+		return project + "/-/blob/" + commit
 	}
+	return ""
+}
 
-	if *title != "" {
-		body = "## " + *title + "\n\n" + body
+func detectBranch() string {
+	for _, key := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "BITBUCKET_BRANCH", "BUILD_SOURCEBRANCHNAME", "CIRCLE_BRANCH", "BUILDKITE_BRANCH", "GIT_BRANCH"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
 	}
+	return ""
+}
 
-	message := map[string]interface{}{
-		"body": body,
+func detectCommit() string {
+	for _, key := range []string{"GITHUB_SHA", "CI_COMMIT_SHA", "BITBUCKET_COMMIT", "BUILD_SOURCEVERSION", "CIRCLE_SHA1", "BUILDKITE_COMMIT", "GIT_COMMIT"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
 	}
+	return ""
+}
 
-	data, err := json.Marshal(message)
-	if err != nil {
-		log.Fatal(err)
+// detectRunId is the id of the ci run, such as the github actions run or the
+// gitlab pipeline
+func detectRunId() string {
+	for _, key := range []string{"GITHUB_RUN_ID", "CI_PIPELINE_ID", "BUILD_BUILDID", "BITBUCKET_BUILD_NUMBER", "CIRCLE_WORKFLOW_ID", "BUILDKITE_BUILD_ID", "BUILD_NUMBER"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
 	}
+	return ""
+}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/issues/%d/comments", *repositorySlug, *pullRequestId)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
-	req.Header.Set("Authorization", "token "+githubAccessToken)
-	req.Header.Set("Content-Type", "application/json")
+// detectJobUrl is the url of the ci job, such as the github actions run or
+// the gitlab job
+func detectJobUrl() string {
+	if server, repository, runId := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); server != "" && repository != "" && runId != "" {
+		return server + "/" + repository + "/actions/runs/" + runId
+	}
+	for _, key := range []string{"CI_JOB_URL", "BUILDKITE_BUILD_URL", "CIRCLE_BUILD_URL", "BUILD_URL"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatal(err)
+func getenvDefault(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-	defer resp.Body.Close()
+	return fallback
+}
 
-	if resp.StatusCode == 201 {
-		fmt.Println("Comment posted to github")
-		return
+func versionString() string {
+	return fmt.Sprintf("xunit-to-github %s (commit %s, built %s)", Version, Commit, BuildDate)
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		for _, command := range commands[1:] {
+			if command.Name == args[0] {
+				command.Run(args[1:])
+				return
+			}
+		}
 	}
 
-	responseBody, _ := ioutil.ReadAll(resp.Body)
-	log.Fatal("err:", string(responseBody))
+	runReport(args)
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+type mergeOptions struct {
+	Format     string
+	OutputFile string
+	Filter     *filterOptions
+	Parsing    *parsingOptions
+}
+
+func newMergeFlags() (*flag.FlagSet, *mergeOptions) {
+	flags := flag.NewFlagSet("xunit-to-github merge", flag.ExitOnError)
+	options := &mergeOptions{}
+	flags.StringVar(&options.Format, "format", "xml", "format: The format to write the merged results in (xml or json)")
+	flags.StringVar(&options.OutputFile, "output-file", "", "output-file: A file to write the merged results to, rather than stdout")
+	options.Filter = addFilterFlags(flags)
+	options.Parsing = addParsingFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runMerge combines xml reports into a single junit xml report or json results
+func runMerge(args []string) {
+	flags, options := newMergeFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	if options.Format != "xml" && options.Format != "json" {
+		logger.Fatal(exitConfigError, "invalid format", "error", fmt.Errorf("unknown format: %s", options.Format))
+	}
+
+	filter, err := options.Filter.compile()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid filter", "error", err)
+	}
+
+	files, missing, err := options.Parsing.findFiles(flags.Args())
+	if err != nil {
+		logger.Fatal(exitParseError, "could not find reports", "error", err)
+	}
+
+	results, err := options.Parsing.parseFiles(ctx, files)
+	if err != nil {
+		logger.Fatal(exitParseError, "could not parse reports", "error", err)
+	}
+	results.MissingPaths = missing
+	results = junit.FilterReport(results, filter)
+
+	var merged junit.Report
+	for _, testsuite := range junit.Merge(results.Suites) {
+		merged.Add(testsuite)
+	}
+
+	write := func(w io.Writer) error {
+		if options.Format == "json" {
+			return writeJSON(w, merged)
+		}
+		return junit.WriteXML(w, merged.Suites)
+	}
+
+	if options.OutputFile != "" {
+		err = writeFileAtomic(options.OutputFile, write)
+	} else {
+		err = write(os.Stdout)
+	}
+	if err != nil {
+		logger.Fatal(exitPublishError, "could not write results", "error", err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func otlpAttribute(key string, value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case int:
+		return map[string]interface{}{"key": key, "value": map[string]interface{}{"intValue": fmt.Sprintf("%d", v)}}
+	case float64:
+		return map[string]interface{}{"key": key, "value": map[string]interface{}{"doubleValue": v}}
+	default:
+		return map[string]interface{}{"key": key, "value": map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}}
+	}
+}
+
+func otlpSpan(traceId string, spanId string, parentSpanId string, name string, start time.Time, end time.Time, failed bool, message string, attributes []map[string]interface{}) map[string]interface{} {
+	status := map[string]interface{}{"code": 1}
+	if failed {
+		status = map[string]interface{}{"code": 2, "message": message}
+	}
+
+	span := map[string]interface{}{
+		"traceId":           traceId,
+		"spanId":            spanId,
+		"name":              name,
+		"kind":              1,
+		"startTimeUnixNano": fmt.Sprintf("%d", start.UnixNano()),
+		"endTimeUnixNano":   fmt.Sprintf("%d", end.UnixNano()),
+		"attributes":        attributes,
+		"status":            status,
+	}
+	if parentSpanId != "" {
+		span["parentSpanId"] = parentSpanId
+	}
+	return span
+}
+
+func randomHex(bytes int) string {
+	b := make([]byte, bytes)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// exportSpans sends the run, each suite, and each testcase as nested spans,
+// continuing the trace from TRACEPARENT when the ci system provides one
+func exportSpans(ctx context.Context, endpoint string, title string, summary junit.Summary, testsuites []junit.Suite) error {
+	traceId := randomHex(16)
+	parentSpanId := ""
+	if parts := strings.Split(os.Getenv("TRACEPARENT"), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+		traceId = parts[1]
+		parentSpanId = parts[2]
+	}
+
+	if title == "" {
+		title = "Test results"
+	}
+
+	now := time.Now()
+	runStart := now
+	var spans []map[string]interface{}
+	runSpanId := randomHex(8)
+	for _, testsuite := range testsuites {
+		duration := testsuite.Duration()
+		start := now.Add(-duration)
+		if started := testsuite.Started(); !started.IsZero() {
+			start = started
+		}
+		if start.Before(runStart) {
+			runStart = start
+		}
+
+		suiteSpanId := randomHex(8)
+		caseStart := start
+		for _, testcase := range testsuite.Cases {
+			caseEnd := caseStart.Add(testcase.Duration())
+			spans = append(spans, otlpSpan(traceId, randomHex(8), suiteSpanId, testcase.Name, caseStart, caseEnd, testcase.Failed(), testcase.Failure.Type, []map[string]interface{}{
+				otlpAttribute("test.name", testcase.Name),
+				otlpAttribute("test.classname", testcase.Classname),
+				otlpAttribute("test.suite", testsuite.Name),
+			}))
+			caseStart = caseEnd
+		}
+
+		spans = append(spans, otlpSpan(traceId, suiteSpanId, runSpanId, testsuite.Name, start, start.Add(duration), testsuite.Failed(), "", []map[string]interface{}{
+			otlpAttribute("test.suite", testsuite.Name),
+			otlpAttribute("test.tests", testsuite.Tests),
+			otlpAttribute("test.failures", testsuite.Failures),
+			otlpAttribute("test.errors", testsuite.Errors),
+			otlpAttribute("test.skipped", testsuite.Skipped),
+			otlpAttribute("host.name", testsuite.Hostname),
+		}))
+	}
+
+	spans = append(spans, otlpSpan(traceId, runSpanId, parentSpanId, title, runStart, now, summary.Failed(), "", []map[string]interface{}{
+		otlpAttribute("test.tests", summary.Tests),
+		otlpAttribute("test.pass_rate", summary.PassRate()),
+	}))
+
+	payload := map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{
+			{
+				"resource": map[string]interface{}{
+					"attributes": []map[string]interface{}{otlpAttribute("service.name", getenvDefault("OTEL_SERVICE_NAME", "xunit-to-github"))},
+				},
+				"scopeSpans": []map[string]interface{}{
+					{"scope": map[string]string{"name": "xunit-to-github"}, "spans": spans},
+				},
+			},
+		},
+	}
+
+	headers := map[string]string{}
+	for _, header := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		parts := strings.SplitN(header, "=", 2)
+		if len(parts) == 2 {
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	_, err := sendJSON(ctx, "POST", strings.TrimSuffix(endpoint, "/")+"/v1/traces", headers, payload, 200)
+	return err
+}
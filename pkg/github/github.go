@@ -0,0 +1,339 @@
+// Package github posts test results to github pull requests
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client posts to the github api on behalf of the access token
+type Client struct {
+	// HTTPClient sends requests, defaulting to http.DefaultClient. Its
+	// transport may wrap another to add tracing, auth, or recording.
+	HTTPClient *http.Client
+	// BaseURL is the root of the api, defaulting to https://api.github.com,
+	// and may point at a github enterprise server or an httptest server
+	BaseURL     string
+	AccessToken string
+}
+
+const defaultBaseURL = "https://api.github.com"
+
+// MaxCommentLength is the longest body github accepts for a comment
+const MaxCommentLength = 65536
+
+// MaxCheckRunOutput is the longest summary or text github accepts for the
+// output of a check run
+const MaxCheckRunOutput = 65535
+
+// MaxAnnotationsPerRequest is the most annotations github accepts in each
+// request to create or update a check run
+const MaxAnnotationsPerRequest = 50
+
+// Error is a response from the api with an unexpected status
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("err: %s", e.Body)
+}
+
+type Comment struct {
+	Id      int64  `json:"id"`
+	HtmlUrl string `json:"html_url"`
+	Body    string `json:"body"`
+}
+
+// Token describes the access token, as reported with every response
+type Token struct {
+	// Scopes are the oauth scopes of the token, which are only reported for
+	// classic personal access tokens, and are otherwise nil
+	Scopes         []string
+	RateLimit      int
+	RateRemaining  int
+	RateLimitReset time.Time
+}
+
+type Repository struct {
+	FullName string `json:"full_name"`
+	Private  bool   `json:"private"`
+	Archived bool   `json:"archived"`
+}
+
+type PullRequest struct {
+	Number  int    `json:"number"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	Locked  bool   `json:"locked"`
+	HtmlUrl string `json:"html_url"`
+	Head    struct {
+		Sha string `json:"sha"`
+	} `json:"head"`
+	Labels []Label `json:"labels"`
+}
+
+// Label is a label applied to an issue or pull request
+type Label struct {
+	Name string `json:"name"`
+}
+
+// HasLabel reports whether the pull request has the label, which github
+// matches regardless of case
+func (p PullRequest) HasLabel(name string) bool {
+	for _, label := range p.Labels {
+		if strings.EqualFold(label.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// PullRequestFile is a file the pull request changes, with a status such as
+// added, modified, removed, or renamed
+type PullRequestFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+type CheckRun struct {
+	Id      int64  `json:"id"`
+	HtmlUrl string `json:"html_url"`
+}
+
+// CheckRunOutput is what a check run shows, with a summary and text of up to
+// MaxCheckRunOutput characters each, and up to MaxAnnotationsPerRequest
+// annotations, which are added to those of earlier requests
+type CheckRunOutput struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Text        string       `json:"text,omitempty"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Annotation marks a line of a file in the repository, with a level of
+// notice, warning, or failure
+type Annotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Level     string `json:"annotation_level"`
+	Title     string `json:"title,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Token fetches the scopes and rate limit of the access token, without
+// counting against the rate limit
+func (c *Client) Token(ctx context.Context) (Token, error) {
+	var token Token
+	header, err := c.get(ctx, "/rate_limit", nil)
+	if err != nil {
+		return token, err
+	}
+
+	if _, ok := header["X-Oauth-Scopes"]; ok {
+		token.Scopes = []string{}
+		for _, scope := range strings.Split(header.Get("X-Oauth-Scopes"), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				token.Scopes = append(token.Scopes, scope)
+			}
+		}
+	}
+	token.RateLimit, _ = strconv.Atoi(header.Get("X-Ratelimit-Limit"))
+	token.RateRemaining, _ = strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+		token.RateLimitReset = time.Unix(reset, 0)
+	}
+	return token, nil
+}
+
+// Repository fetches the repository
+func (c *Client) Repository(ctx context.Context, repositorySlug string) (Repository, error) {
+	var repository Repository
+	_, err := c.get(ctx, "/repos/"+repositorySlug, &repository)
+	return repository, err
+}
+
+// PullRequest fetches the pull request
+func (c *Client) PullRequest(ctx context.Context, repositorySlug string, pullRequestId int) (PullRequest, error) {
+	var pullRequest PullRequest
+	_, err := c.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d", repositorySlug, pullRequestId), &pullRequest)
+	return pullRequest, err
+}
+
+// pullRequestFilesPerPage is the most files github lists per page, of the
+// 3000 it lists for a pull request in all
+const pullRequestFilesPerPage = 100
+
+// PullRequestFiles fetches every file the pull request changes
+func (c *Client) PullRequestFiles(ctx context.Context, repositorySlug string, pullRequestId int) ([]PullRequestFile, error) {
+	var files []PullRequestFile
+	for page := 1; ; page++ {
+		var pageFiles []PullRequestFile
+		if _, err := c.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=%d&page=%d", repositorySlug, pullRequestId, pullRequestFilesPerPage, page), &pageFiles); err != nil {
+			return files, err
+		}
+		files = append(files, pageFiles...)
+		if len(pageFiles) < pullRequestFilesPerPage {
+			return files, nil
+		}
+	}
+}
+
+// commentsPerPage is the most comments github lists per page
+const commentsPerPage = 100
+
+// Comments fetches every comment on the pull request, oldest first
+func (c *Client) Comments(ctx context.Context, repositorySlug string, pullRequestId int) ([]Comment, error) {
+	var comments []Comment
+	for page := 1; ; page++ {
+		var pageComments []Comment
+		if _, err := c.get(ctx, fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=%d&page=%d", repositorySlug, pullRequestId, commentsPerPage, page), &pageComments); err != nil {
+			return comments, err
+		}
+		comments = append(comments, pageComments...)
+		if len(pageComments) < commentsPerPage {
+			return comments, nil
+		}
+	}
+}
+
+// get fetches the path of the api, decoding the response into v when it is
+// not nil and returning its headers
+func (c *Client) get(ctx context.Context, path string, v interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+c.AccessToken)
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return resp.Header, &Error{StatusCode: resp.StatusCode, Body: string(responseBody)}
+	}
+	if v != nil {
+		if err := json.Unmarshal(responseBody, v); err != nil {
+			return resp.Header, err
+		}
+	}
+	return resp.Header, nil
+}
+
+func (c *Client) baseURL() string {
+	baseURL := strings.TrimSuffix(c.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return baseURL
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// PostComment posts the body as a comment on the pull request, or edits the
+// existing comment when commentId is set
+func (c *Client) PostComment(ctx context.Context, repositorySlug string, pullRequestId int, commentId int64, body string) (Comment, error) {
+	baseURL := c.baseURL()
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", baseURL, repositorySlug, pullRequestId)
+	method, expectedStatus := "POST", 201
+	if commentId != 0 {
+		url = fmt.Sprintf("%s/repos/%s/issues/comments/%d", baseURL, repositorySlug, commentId)
+		method, expectedStatus = "PATCH", 200
+	}
+
+	message := map[string]interface{}{
+		"body": body,
+	}
+
+	var comment Comment
+	responseBody, err := c.sendJSON(ctx, method, url, message, expectedStatus)
+	if err != nil {
+		return comment, err
+	}
+
+	json.Unmarshal(responseBody, &comment)
+	return comment, nil
+}
+
+// CreateCheckRun creates a completed check run on the commit with the
+// conclusion, such as success, failure, or neutral
+func (c *Client) CreateCheckRun(ctx context.Context, repositorySlug string, name string, headSha string, conclusion string, output CheckRunOutput) (CheckRun, error) {
+	message := map[string]interface{}{
+		"name":       name,
+		"head_sha":   headSha,
+		"status":     "completed",
+		"conclusion": conclusion,
+		"output":     output,
+	}
+
+	var checkRun CheckRun
+	responseBody, err := c.sendJSON(ctx, "POST", fmt.Sprintf("%s/repos/%s/check-runs", c.baseURL(), repositorySlug), message, 201)
+	if err != nil {
+		return checkRun, err
+	}
+
+	json.Unmarshal(responseBody, &checkRun)
+	return checkRun, nil
+}
+
+// UpdateCheckRun replaces the title, summary, and text of the check run,
+// adding the annotations of the output to those it already has
+func (c *Client) UpdateCheckRun(ctx context.Context, repositorySlug string, checkRunId int64, output CheckRunOutput) (CheckRun, error) {
+	message := map[string]interface{}{
+		"output": output,
+	}
+
+	var checkRun CheckRun
+	responseBody, err := c.sendJSON(ctx, "PATCH", fmt.Sprintf("%s/repos/%s/check-runs/%d", c.baseURL(), repositorySlug, checkRunId), message, 200)
+	if err != nil {
+		return checkRun, err
+	}
+
+	json.Unmarshal(responseBody, &checkRun)
+	return checkRun, nil
+}
+
+func (c *Client) sendJSON(ctx context.Context, method string, url string, payload interface{}, expectedStatus int) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+c.AccessToken)
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != expectedStatus {
+		return responseBody, &Error{StatusCode: resp.StatusCode, Body: string(responseBody)}
+	}
+
+	return responseBody, nil
+}
@@ -0,0 +1,256 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// request is a request the test server received, with its decoded json body
+type request struct {
+	Method        string
+	Path          string
+	Authorization string
+	Body          map[string]interface{}
+}
+
+// newServer starts a server that records each request and responds with
+// handler, returning a client for it along with the requests it received
+func newServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*Client, *[]request) {
+	t.Helper()
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received := request{Method: r.Method, Path: r.URL.RequestURI(), Authorization: r.Header.Get("Authorization")}
+		if data, _ := ioutil.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &received.Body); err != nil {
+				t.Errorf("request body is not json: %s", err)
+			}
+		}
+		requests = append(requests, received)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return &Client{HTTPClient: server.Client(), BaseURL: server.URL + "/", AccessToken: "secret"}, &requests
+}
+
+func respond(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+func TestPostComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		commentId int64
+		status    int
+		method    string
+		path      string
+	}{
+		{"creates a comment", 0, 201, "POST", "/repos/owner/repo/issues/7/comments"},
+		{"edits an existing comment", 42, 200, "PATCH", "/repos/owner/repo/issues/comments/42"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+				respond(w, test.status, `{"id": 42, "html_url": "https://github.com/owner/repo/pull/7#issuecomment-42", "body": "results"}`)
+			})
+
+			comment, err := client.PostComment(context.Background(), "owner/repo", 7, test.commentId, "results")
+			if err != nil {
+				t.Fatalf("PostComment() error = %s", err)
+			}
+			if comment.Id != 42 || comment.HtmlUrl != "https://github.com/owner/repo/pull/7#issuecomment-42" {
+				t.Errorf("PostComment() = %+v", comment)
+			}
+
+			if len(*requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(*requests))
+			}
+			received := (*requests)[0]
+			if received.Method != test.method || received.Path != test.path {
+				t.Errorf("request = %s %s, want %s %s", received.Method, received.Path, test.method, test.path)
+			}
+			if received.Authorization != "token secret" {
+				t.Errorf("Authorization = %q, want %q", received.Authorization, "token secret")
+			}
+			if received.Body["body"] != "results" {
+				t.Errorf("body = %v, want %q", received.Body["body"], "results")
+			}
+		})
+	}
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		pages int
+	}{
+		{"no comments", 0, 1},
+		{"a partial page", 3, 1},
+		{"a full page", commentsPerPage, 2},
+		{"several pages", commentsPerPage*2 + 5, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+				comments := []Comment{}
+				for id := (page-1)*perPage + 1; id <= page*perPage && id <= test.total; id++ {
+					comments = append(comments, Comment{Id: int64(id)})
+				}
+				data, _ := json.Marshal(comments)
+				respond(w, 200, string(data))
+			})
+
+			comments, err := client.Comments(context.Background(), "owner/repo", 7)
+			if err != nil {
+				t.Fatalf("Comments() error = %s", err)
+			}
+			if len(comments) != test.total {
+				t.Fatalf("Comments() returned %d comments, want %d", len(comments), test.total)
+			}
+			for i, comment := range comments {
+				if comment.Id != int64(i+1) {
+					t.Fatalf("comment %d has id %d, want %d", i, comment.Id, i+1)
+				}
+			}
+
+			if len(*requests) != test.pages {
+				t.Fatalf("got %d requests, want %d", len(*requests), test.pages)
+			}
+			for i, received := range *requests {
+				want := fmt.Sprintf("/repos/owner/repo/issues/7/comments?per_page=%d&page=%d", commentsPerPage, i+1)
+				if received.Method != "GET" || received.Path != want {
+					t.Errorf("request %d = %s %s, want GET %s", i, received.Method, received.Path, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateCheckRun(t *testing.T) {
+	client, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, 201, `{"id": 5, "html_url": "https://github.com/owner/repo/runs/5"}`)
+	})
+
+	output := CheckRunOutput{
+		Title:       "1 failure",
+		Summary:     "1..2 (api)",
+		Annotations: []Annotation{{Path: "api_test.go", StartLine: 12, EndLine: 12, Level: "failure", Message: "boom"}},
+	}
+	checkRun, err := client.CreateCheckRun(context.Background(), "owner/repo", "Unit tests", "abc123", "failure", output)
+	if err != nil {
+		t.Fatalf("CreateCheckRun() error = %s", err)
+	}
+	if checkRun.Id != 5 || checkRun.HtmlUrl != "https://github.com/owner/repo/runs/5" {
+		t.Errorf("CreateCheckRun() = %+v", checkRun)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	received := (*requests)[0]
+	if received.Method != "POST" || received.Path != "/repos/owner/repo/check-runs" {
+		t.Errorf("request = %s %s, want POST /repos/owner/repo/check-runs", received.Method, received.Path)
+	}
+	for key, want := range map[string]string{"name": "Unit tests", "head_sha": "abc123", "status": "completed", "conclusion": "failure"} {
+		if received.Body[key] != want {
+			t.Errorf("%s = %v, want %q", key, received.Body[key], want)
+		}
+	}
+	sent, _ := received.Body["output"].(map[string]interface{})
+	if annotations, _ := sent["annotations"].([]interface{}); sent["title"] != "1 failure" || len(annotations) != 1 {
+		t.Errorf("output = %v", received.Body["output"])
+	}
+}
+
+func TestUpdateCheckRun(t *testing.T) {
+	client, requests := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, 200, `{"id": 5, "html_url": "https://github.com/owner/repo/runs/5"}`)
+	})
+
+	checkRun, err := client.UpdateCheckRun(context.Background(), "owner/repo", 5, CheckRunOutput{Title: "1 failure", Summary: "1..2 (api)"})
+	if err != nil {
+		t.Fatalf("UpdateCheckRun() error = %s", err)
+	}
+	if checkRun.Id != 5 {
+		t.Errorf("UpdateCheckRun() = %+v", checkRun)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*requests))
+	}
+	received := (*requests)[0]
+	if received.Method != "PATCH" || received.Path != "/repos/owner/repo/check-runs/5" {
+		t.Errorf("request = %s %s, want PATCH /repos/owner/repo/check-runs/5", received.Method, received.Path)
+	}
+	if _, ok := received.Body["conclusion"]; ok {
+		t.Errorf("conclusion = %v, want it left unchanged", received.Body["conclusion"])
+	}
+	if sent, _ := received.Body["output"].(map[string]interface{}); sent["summary"] != "1..2 (api)" {
+		t.Errorf("output = %v", received.Body["output"])
+	}
+}
+
+func TestUnexpectedStatus(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		expected int
+		call     func(client *Client) error
+	}{
+		{"PostComment", 201, func(client *Client) error {
+			_, err := client.PostComment(ctx, "owner/repo", 7, 0, "results")
+			return err
+		}},
+		{"PostComment editing", 200, func(client *Client) error {
+			_, err := client.PostComment(ctx, "owner/repo", 7, 42, "results")
+			return err
+		}},
+		{"Comments", 200, func(client *Client) error {
+			_, err := client.Comments(ctx, "owner/repo", 7)
+			return err
+		}},
+		{"CreateCheckRun", 201, func(client *Client) error {
+			_, err := client.CreateCheckRun(ctx, "owner/repo", "Unit tests", "abc123", "success", CheckRunOutput{})
+			return err
+		}},
+		{"UpdateCheckRun", 200, func(client *Client) error {
+			_, err := client.UpdateCheckRun(ctx, "owner/repo", 5, CheckRunOutput{})
+			return err
+		}},
+	}
+	for _, test := range tests {
+		for _, status := range []int{200, 201, 403, 422, 500} {
+			t.Run(fmt.Sprintf("%s %d", test.name, status), func(t *testing.T) {
+				client, _ := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+					respond(w, status, `[]`)
+				})
+
+				err := test.call(client)
+				if status == test.expected {
+					if err != nil {
+						t.Fatalf("got %s, want no error", err)
+					}
+					return
+				}
+				var apiErr *Error
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("got %v, want *Error", err)
+				}
+				if apiErr.StatusCode != status || apiErr.Body != `[]` {
+					t.Errorf("Error = %+v", apiErr)
+				}
+			})
+		}
+	}
+}
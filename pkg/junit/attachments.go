@@ -0,0 +1,69 @@
+package junit
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Attachment is a file a testcase attached to its results, such as a
+// screenshot or log, with a url to view it at once it is uploaded
+type Attachment struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Url  string `json:"url,omitempty"`
+}
+
+// imageExtensions are the extensions of the attachments that are shown
+// inline rather than linked
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg"}
+
+// Image reports whether the attachment is an image, by its extension
+func (a Attachment) Image() bool {
+	extension := strings.ToLower(path.Ext(a.Path))
+	for _, image := range imageExtensions {
+		if extension == image {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentPattern matches the [[ATTACHMENT|path]] that jenkins and gitlab
+// read from the output of a testcase
+var attachmentPattern = regexp.MustCompile(`\[\[ATTACHMENT\|([^\]\r\n]+)\]\]`)
+
+// attachments finds the files the testcase attached, from [[ATTACHMENT|path]]
+// in its output or failure message, and from properties named attachment or
+// starting with attachment, as written by allure and other junit adapters,
+// which name the attachment after the rest of the property name, such as
+// attachment.screenshot
+func attachments(element xmlTestcase, message string) []Attachment {
+	var found []Attachment
+	seen := map[string]bool{}
+	add := func(name string, file string) {
+		file = strings.TrimSpace(file)
+		if file == "" || seen[file] {
+			return
+		}
+		seen[file] = true
+		if name == "" {
+			name = path.Base(strings.ReplaceAll(file, "\\", "/"))
+		}
+		found = append(found, Attachment{Name: name, Path: file})
+	}
+
+	for _, text := range []string{element.SystemOut, element.SystemErr, message} {
+		for _, match := range attachmentPattern.FindAllStringSubmatch(text, -1) {
+			add("", match[1])
+		}
+	}
+	for _, property := range element.Properties {
+		if !strings.HasPrefix(strings.ToLower(property.Name), "attachment") {
+			continue
+		}
+		name := strings.TrimPrefix(property.Name[len("attachment"):], "s")
+		add(strings.TrimLeft(name, ".:_-"), property.Value)
+	}
+	return found
+}
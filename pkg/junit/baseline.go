@@ -0,0 +1,52 @@
+package junit
+
+import (
+	"sort"
+	"time"
+)
+
+// Slowdown is a testcase that took longer than it did in a baseline run, with
+// the baseline time in seconds
+type Slowdown struct {
+	Case
+	Baseline float64 `json:"baseline"`
+}
+
+// Increase is how much longer the testcase took than in the baseline run
+func (s Slowdown) Increase() time.Duration {
+	return s.Duration() - seconds(s.Baseline)
+}
+
+// CompareDurations finds the testcases in the report that took more than
+// factor times as long as in the baseline, and at least minimum longer, so
+// that fast testcases varying by milliseconds are not flagged. The
+// slowdowns are returned with the largest increase first.
+func CompareDurations(baseline Report, report Report, factor float64, minimum time.Duration) []Slowdown {
+	times := map[string]float64{}
+	for _, suite := range baseline.Suites {
+		for _, testcase := range suite.Cases {
+			if _, ok := times[testcase.Id()]; !ok {
+				times[testcase.Id()] = testcase.Time
+			}
+		}
+	}
+
+	var slowdowns []Slowdown
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			previous, ok := times[testcase.Id()]
+			if !ok || testcase.Status == StatusSkipped {
+				continue
+			}
+			slowdown := Slowdown{testcase, previous}
+			if testcase.Time > previous*factor && slowdown.Increase() >= minimum {
+				slowdowns = append(slowdowns, slowdown)
+			}
+		}
+	}
+
+	sort.SliceStable(slowdowns, func(i, j int) bool {
+		return slowdowns[i].Increase() > slowdowns[j].Increase()
+	})
+	return slowdowns
+}
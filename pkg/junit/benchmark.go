@@ -0,0 +1,219 @@
+package junit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Benchmark is the result of a benchmark, as the time each operation took,
+// and for go, the memory each operation allocated
+type Benchmark struct {
+	Name string `json:"name"`
+	// Nanoseconds is the mean time each operation took
+	Nanoseconds float64 `json:"ns_per_op"`
+	// BytesPerOp and AllocsPerOp are the memory each operation allocated,
+	// when the benchmark measured it
+	BytesPerOp  *float64 `json:"bytes_per_op,omitempty"`
+	AllocsPerOp *float64 `json:"allocs_per_op,omitempty"`
+	// Baseline is the time each operation took in a baseline run, when the
+	// benchmark was compared to one
+	Baseline *float64 `json:"baseline_ns_per_op,omitempty"`
+	// Regression is whether the benchmark became slower than its baseline by
+	// more than the threshold it was compared with
+	Regression bool `json:"regression,omitempty"`
+	runs       int
+}
+
+// Change is the percent the time of each operation changed from the
+// baseline, reporting whether there is a baseline to compare to
+func (b Benchmark) Change() (float64, bool) {
+	if b.Baseline == nil || *b.Baseline == 0 {
+		return 0, false
+	}
+	return (b.Nanoseconds / *b.Baseline - 1) * 100, true
+}
+
+// goBenchmarkPattern matches a result line of go test -bench, such as
+// BenchmarkParse-8   1000   1234 ns/op   56 B/op   2 allocs/op
+var goBenchmarkPattern = regexp.MustCompile(`^(Benchmark\S*?)(?:-\d+)?\s+\d+\s+(.*\bns/op\b.*)$`)
+
+// ParseBenchmarks parses the output of go test -bench, or the json results of
+// jmh or pytest-benchmark. The runs of a benchmark run more than once, as with
+// go test -count, are averaged.
+func ParseBenchmarks(data []byte) ([]Benchmark, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		return parseJmh(trimmed)
+	}
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return parsePytestBenchmark(trimmed)
+	}
+	return parseGoBenchmarks(data)
+}
+
+func parseGoBenchmarks(data []byte) ([]Benchmark, error) {
+	var benchmarks []Benchmark
+	index := map[string]int{}
+	pkg := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg: "))
+			continue
+		}
+		match := goBenchmarkPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		benchmark := Benchmark{Name: match[1], runs: 1}
+		if pkg != "" {
+			benchmark.Name = pkg + "." + benchmark.Name
+		}
+		fields := strings.Fields(match[2])
+		for i := 0; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				benchmark.Nanoseconds = value
+			case "B/op":
+				benchmark.BytesPerOp = &value
+			case "allocs/op":
+				benchmark.AllocsPerOp = &value
+			}
+		}
+
+		if i, ok := index[benchmark.Name]; ok {
+			benchmarks[i] = benchmarks[i].average(benchmark)
+			continue
+		}
+		index[benchmark.Name] = len(benchmarks)
+		benchmarks = append(benchmarks, benchmark)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(benchmarks) == 0 {
+		return nil, fmt.Errorf("no go test -bench, jmh, or pytest-benchmark results found")
+	}
+	return benchmarks, nil
+}
+
+// average combines another run of the benchmark into its running average
+func (b Benchmark) average(run Benchmark) Benchmark {
+	mean := func(total *float64, value *float64) *float64 {
+		if total == nil || value == nil {
+			return total
+		}
+		averaged := (*total*float64(b.runs) + *value) / float64(b.runs+1)
+		return &averaged
+	}
+	b.Nanoseconds = (b.Nanoseconds*float64(b.runs) + run.Nanoseconds) / float64(b.runs+1)
+	b.BytesPerOp = mean(b.BytesPerOp, run.BytesPerOp)
+	b.AllocsPerOp = mean(b.AllocsPerOp, run.AllocsPerOp)
+	b.runs++
+	return b
+}
+
+// timeUnits are the nanoseconds in each unit of time jmh reports in
+var timeUnits = map[string]float64{"ns": 1, "us": 1e3, "µs": 1e3, "ms": 1e6, "s": 1e9, "min": 60e9}
+
+func parseJmh(data []byte) ([]Benchmark, error) {
+	var results []struct {
+		Benchmark     string            `json:"benchmark"`
+		Params        map[string]string `json:"params"`
+		PrimaryMetric struct {
+			Score     float64 `json:"score"`
+			ScoreUnit string  `json:"scoreUnit"`
+		} `json:"primaryMetric"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+
+	var benchmarks []Benchmark
+	for _, result := range results {
+		// scores are either the time of an operation, such as us/op, or for
+		// throughput, the operations in a time, such as ops/s
+		unit := result.PrimaryMetric.ScoreUnit
+		var nanoseconds float64
+		if strings.HasSuffix(unit, "/op") {
+			nanoseconds = result.PrimaryMetric.Score * timeUnits[strings.TrimSuffix(unit, "/op")]
+		} else if strings.HasPrefix(unit, "ops/") && result.PrimaryMetric.Score > 0 {
+			nanoseconds = timeUnits[strings.TrimPrefix(unit, "ops/")] / result.PrimaryMetric.Score
+		}
+		if nanoseconds == 0 {
+			return nil, fmt.Errorf("%s: unsupported score unit %s", result.Benchmark, unit)
+		}
+
+		name := result.Benchmark
+		if len(result.Params) > 0 {
+			var params []string
+			for key, value := range result.Params {
+				params = append(params, key+"="+value)
+			}
+			sort.Strings(params)
+			name += "(" + strings.Join(params, ",") + ")"
+		}
+		benchmarks = append(benchmarks, Benchmark{Name: name, Nanoseconds: nanoseconds})
+	}
+	return benchmarks, nil
+}
+
+func parsePytestBenchmark(data []byte) ([]Benchmark, error) {
+	var results struct {
+		Benchmarks []struct {
+			Name     string `json:"name"`
+			Fullname string `json:"fullname"`
+			Stats    struct {
+				Mean float64 `json:"mean"`
+			} `json:"stats"`
+		} `json:"benchmarks"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	if results.Benchmarks == nil {
+		return nil, fmt.Errorf("no go test -bench, jmh, or pytest-benchmark results found")
+	}
+
+	var benchmarks []Benchmark
+	for _, result := range results.Benchmarks {
+		name := result.Fullname
+		if name == "" {
+			name = result.Name
+		}
+		benchmarks = append(benchmarks, Benchmark{Name: name, Nanoseconds: result.Stats.Mean * 1e9})
+	}
+	return benchmarks, nil
+}
+
+// CompareBenchmarks sets the baseline of each benchmark the baseline also
+// ran, marking those more than threshold percent slower as regressions
+func CompareBenchmarks(baseline []Benchmark, benchmarks []Benchmark, threshold float64) []Benchmark {
+	times := map[string]float64{}
+	for _, benchmark := range baseline {
+		times[benchmark.Name] = benchmark.Nanoseconds
+	}
+
+	compared := make([]Benchmark, len(benchmarks))
+	for i, benchmark := range benchmarks {
+		if previous, ok := times[benchmark.Name]; ok {
+			benchmark.Baseline = &previous
+			change, ok := benchmark.Change()
+			benchmark.Regression = ok && change > threshold
+		}
+		compared[i] = benchmark
+	}
+	return compared
+}
@@ -0,0 +1,93 @@
+package junit
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Cluster is a group of failing testcases whose failures share a signature,
+// such as many tests failing because a database is unreachable
+type Cluster struct {
+	Signature string `json:"signature"`
+	// Message is the normalized first line of the failures
+	Message string `json:"message"`
+	Cases   []Case `json:"testcases"`
+}
+
+var volatilePatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`), "<uuid>"},
+	{regexp.MustCompile(`(?i)0x[0-9a-f]+`), "0x?"},
+	{regexp.MustCompile(`\d{6,}`), "#"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// FailureSignature normalizes the type and first line of the testcase's
+// failure, replacing values such as uuids, addresses, and long numbers that
+// vary between otherwise identical failures
+func FailureSignature(testcase Case) string {
+	signature := normalizeMessage(testcase.Failure.Message)
+	if testcase.Failure.Type != "" {
+		signature = testcase.Failure.Type + ": " + signature
+	}
+	return signature
+}
+
+func normalizeMessage(message string) string {
+	message = firstLine(message)
+	for _, volatile := range volatilePatterns {
+		message = volatile.pattern.ReplaceAllString(message, volatile.replacement)
+	}
+	return message
+}
+
+// ClusterFailures groups the failing testcases of the report by their
+// failure signature, returning the groups of at least minimum testcases with
+// the largest first. Failures without a type or message, such as those only
+// marked by a status attribute, are not grouped.
+func ClusterFailures(report Report, minimum int) []Cluster {
+	var signatures []string
+	clusters := map[string]*Cluster{}
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			if !testcase.Failed() {
+				continue
+			}
+
+			signature := FailureSignature(testcase)
+			if signature == "" {
+				continue
+			}
+			cluster, ok := clusters[signature]
+			if !ok {
+				cluster = &Cluster{Signature: signature, Message: normalizeMessage(testcase.Failure.Message)}
+				clusters[signature] = cluster
+				signatures = append(signatures, signature)
+			}
+			cluster.Cases = append(cluster.Cases, testcase)
+		}
+	}
+
+	var grouped []Cluster
+	for _, signature := range signatures {
+		if len(clusters[signature].Cases) >= minimum {
+			grouped = append(grouped, *clusters[signature])
+		}
+	}
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return len(grouped[i].Cases) > len(grouped[j].Cases)
+	})
+	return grouped
+}
+
+func firstLine(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
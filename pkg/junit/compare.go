@@ -0,0 +1,82 @@
+package junit
+
+import (
+	"time"
+)
+
+// Comparison is the difference between an old and a new run
+type Comparison struct {
+	Old         Summary    `json:"old"`
+	New         Summary    `json:"new"`
+	OldDuration float64    `json:"old_duration"`
+	NewDuration float64    `json:"new_duration"`
+	NewFailures []Case     `json:"new_failures"`
+	Fixed       []Case     `json:"fixed"`
+	Added       []Case     `json:"added"`
+	Removed     []Case     `json:"removed"`
+	Slower      []Slowdown `json:"slower"`
+}
+
+// Changed reports whether any testcase changed status, was added or removed,
+// or became slower
+func (c Comparison) Changed() bool {
+	return len(c.NewFailures) > 0 || len(c.Fixed) > 0 || len(c.Added) > 0 || len(c.Removed) > 0 || len(c.Slower) > 0
+}
+
+// Compare finds the testcases that started failing, were fixed, were added,
+// or were removed between the old and new runs, along with those that became
+// slower as in CompareDurations. Testcases are matched by id, and the first
+// occurrence of each is used.
+func Compare(old Report, current Report, factor float64, minimum time.Duration) Comparison {
+	comparison := Comparison{
+		Old:         old.Summary,
+		New:         current.Summary,
+		OldDuration: old.Duration().Seconds(),
+		NewDuration: current.Duration().Seconds(),
+		Slower:      CompareDurations(old, current, factor, minimum),
+	}
+
+	oldCases := casesById(old)
+	newCases := casesById(current)
+	for _, testcase := range uniqueCases(current) {
+		previous, ok := oldCases[testcase.Id()]
+		switch {
+		case !ok:
+			comparison.Added = append(comparison.Added, testcase)
+		case testcase.Failed() && !previous.Failed():
+			comparison.NewFailures = append(comparison.NewFailures, testcase)
+		case previous.Failed() && !testcase.Failed() && testcase.Status != StatusSkipped:
+			comparison.Fixed = append(comparison.Fixed, testcase)
+		}
+	}
+
+	for _, testcase := range uniqueCases(old) {
+		if _, ok := newCases[testcase.Id()]; !ok {
+			comparison.Removed = append(comparison.Removed, testcase)
+		}
+	}
+	return comparison
+}
+
+// uniqueCases returns the first occurrence of each testcase in the report
+func uniqueCases(report Report) []Case {
+	var cases []Case
+	seen := map[string]bool{}
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			if !seen[testcase.Id()] {
+				seen[testcase.Id()] = true
+				cases = append(cases, testcase)
+			}
+		}
+	}
+	return cases
+}
+
+func casesById(report Report) map[string]Case {
+	cases := map[string]Case{}
+	for _, testcase := range uniqueCases(report) {
+		cases[testcase.Id()] = testcase
+	}
+	return cases
+}
@@ -0,0 +1,221 @@
+package junit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Coverage is the line coverage of a run, overall and for each package, from
+// cobertura or lcov reports
+type Coverage struct {
+	Lines    int               `json:"lines"`
+	Covered  int               `json:"covered"`
+	Packages []PackageCoverage `json:"packages"`
+	// Baseline is the percent of lines a baseline run covered, when the
+	// coverage was compared to one
+	Baseline *float64 `json:"baseline,omitempty"`
+}
+
+// PackageCoverage is the line coverage of a package, or for lcov, a directory
+type PackageCoverage struct {
+	Name     string   `json:"name"`
+	Lines    int      `json:"lines"`
+	Covered  int      `json:"covered"`
+	Baseline *float64 `json:"baseline,omitempty"`
+}
+
+// Percent is the percent of lines covered, or 0 when there are no lines
+func (c Coverage) Percent() float64 {
+	return percent(c.Covered, c.Lines)
+}
+
+// Percent is the percent of lines of the package covered, or 0 when there
+// are no lines
+func (p PackageCoverage) Percent() float64 {
+	return percent(p.Covered, p.Lines)
+}
+
+func percent(covered int, lines int) float64 {
+	if lines == 0 {
+		return 0
+	}
+	return float64(covered) / float64(lines) * 100
+}
+
+type coberturaReport struct {
+	XMLName  xml.Name `xml:"coverage"`
+	Packages []struct {
+		Name    string `xml:"name,attr"`
+		Classes []struct {
+			Filename string `xml:"filename,attr"`
+			Lines    []struct {
+				Number int `xml:"number,attr"`
+				Hits   int `xml:"hits,attr"`
+			} `xml:"lines>line"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+// ParseCoverage parses a cobertura xml or lcov coverage report, naming the
+// directories of lcov files relative to the repository at root
+func ParseCoverage(data []byte, root string) (Coverage, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return parseCobertura(data)
+	}
+	return parseLcov(data, root)
+}
+
+func parseCobertura(data []byte) (Coverage, error) {
+	var report coberturaReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return Coverage{}, err
+	}
+
+	var coverage Coverage
+	for _, pkg := range report.Packages {
+		// classes of the same file, such as inner classes, repeat its lines
+		lines := map[string]bool{}
+		packageCoverage := PackageCoverage{Name: pkg.Name}
+		for _, class := range pkg.Classes {
+			for _, line := range class.Lines {
+				key := fmt.Sprintf("%s:%d", class.Filename, line.Number)
+				if covered, seen := lines[key]; seen {
+					if !covered && line.Hits > 0 {
+						lines[key] = true
+						packageCoverage.Covered++
+					}
+					continue
+				}
+				lines[key] = line.Hits > 0
+				packageCoverage.Lines++
+				if line.Hits > 0 {
+					packageCoverage.Covered++
+				}
+			}
+		}
+		if packageCoverage.Name == "" {
+			packageCoverage.Name = "."
+		}
+		coverage.Packages = append(coverage.Packages, packageCoverage)
+	}
+	return combineCoverage(coverage), nil
+}
+
+func parseLcov(data []byte, root string) (Coverage, error) {
+	var coverage Coverage
+	var file string
+	var lines, covered, found, hit int
+	records := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		key, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			key, value = line[:i], line[i+1:]
+		}
+
+		switch key {
+		case "SF":
+			file, lines, covered, found, hit = value, 0, 0, -1, -1
+		case "DA":
+			fields := strings.Split(value, ",")
+			if len(fields) < 2 {
+				return coverage, fmt.Errorf("line %d: invalid DA record %s", number, value)
+			}
+			hits, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return coverage, fmt.Errorf("line %d: invalid DA record %s", number, value)
+			}
+			lines++
+			if hits > 0 {
+				covered++
+			}
+		case "LF":
+			found, _ = strconv.Atoi(value)
+		case "LH":
+			hit, _ = strconv.Atoi(value)
+		case "end_of_record":
+			// the totals are preferred, as some tools only write them
+			if found >= 0 && hit >= 0 {
+				lines, covered = found, hit
+			}
+			directory := path.Dir(strings.ReplaceAll(file, "\\", "/"))
+			if relative, ok := repositoryPath(directory, root); ok {
+				directory = relative
+			}
+			coverage.Packages = append(coverage.Packages, PackageCoverage{Name: directory, Lines: lines, Covered: covered})
+			records++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return coverage, err
+	}
+	if records == 0 {
+		return coverage, fmt.Errorf("not a cobertura or lcov coverage report")
+	}
+	return combineCoverage(coverage), nil
+}
+
+// CombineCoverage combines the coverage of several reports, such as those of
+// each service of a repository, adding up the packages they share
+func CombineCoverage(coverages ...Coverage) Coverage {
+	var combined Coverage
+	for _, coverage := range coverages {
+		combined.Packages = append(combined.Packages, coverage.Packages...)
+	}
+	return combineCoverage(combined)
+}
+
+// combineCoverage adds up the packages of the coverage with the same name,
+// sorting them by name, and totals them
+func combineCoverage(coverage Coverage) Coverage {
+	index := map[string]int{}
+	var packages []PackageCoverage
+	for _, pkg := range coverage.Packages {
+		if i, ok := index[pkg.Name]; ok {
+			packages[i].Lines += pkg.Lines
+			packages[i].Covered += pkg.Covered
+			continue
+		}
+		index[pkg.Name] = len(packages)
+		packages = append(packages, PackageCoverage{Name: pkg.Name, Lines: pkg.Lines, Covered: pkg.Covered})
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	combined := Coverage{Packages: packages}
+	for _, pkg := range packages {
+		combined.Lines += pkg.Lines
+		combined.Covered += pkg.Covered
+	}
+	return combined
+}
+
+// CompareCoverage sets the baseline of the coverage, and of each package the
+// baseline also has, to the percent of lines the baseline covered
+func CompareCoverage(baseline Coverage, coverage Coverage) Coverage {
+	compared := coverage
+	overall := baseline.Percent()
+	compared.Baseline = &overall
+
+	percents := map[string]float64{}
+	for _, pkg := range baseline.Packages {
+		percents[pkg.Name] = pkg.Percent()
+	}
+	compared.Packages = make([]PackageCoverage, len(coverage.Packages))
+	for i, pkg := range coverage.Packages {
+		if previous, ok := percents[pkg.Name]; ok {
+			pkg.Baseline = &previous
+		}
+		compared.Packages[i] = pkg
+	}
+	return compared
+}
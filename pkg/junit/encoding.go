@@ -0,0 +1,113 @@
+package junit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeBOM strips the byte order mark that reports written on windows often
+// start with, converting utf-16 reports to utf-8
+func decodeBOM(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	bom, _ := buffered.Peek(len(utf8BOM))
+	switch {
+	case bytes.HasPrefix(bom, utf8BOM):
+		buffered.Discard(len(utf8BOM))
+	case bytes.HasPrefix(bom, []byte{0xFF, 0xFE}):
+		buffered.Discard(2)
+		return newUTF16Reader(buffered, binary.LittleEndian)
+	case bytes.HasPrefix(bom, []byte{0xFE, 0xFF}):
+		buffered.Discard(2)
+		return newUTF16Reader(buffered, binary.BigEndian)
+	}
+	return buffered
+}
+
+// charsetReader accepts the utf-16 encodings declared by reports that
+// decodeBOM has already converted to utf-8
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "utf-16", "utf-16le", "utf-16be":
+		return input, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %s", label)
+}
+
+// newUTF16Reader reads utf-16 in the byte order as utf-8, replacing unpaired
+// surrogates with the unicode replacement character
+func newUTF16Reader(r *bufio.Reader, order binary.ByteOrder) io.Reader {
+	readUnit := func() (rune, error) {
+		unit, err := r.Peek(2)
+		if len(unit) < 2 {
+			// a trailing odd byte cannot be a character
+			if err == nil || err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		r.Discard(2)
+		return rune(order.Uint16(unit)), nil
+	}
+
+	return &runeReader{
+		next: func() (rune, error) {
+			char, err := readUnit()
+			if err != nil || char < 0xD800 || char > 0xDBFF {
+				return char, err
+			}
+
+			// only consume the next unit when it completes the pair
+			if unit, _ := r.Peek(2); len(unit) == 2 {
+				if low := rune(order.Uint16(unit)); low >= 0xDC00 && low <= 0xDFFF {
+					r.Discard(2)
+					return utf16.DecodeRune(char, low), nil
+				}
+			}
+			return utf8.RuneError, nil
+		},
+		ready: func() bool { return r.Buffered() >= 2 },
+	}
+}
+
+// runeReader reads the characters returned by next as utf-8. Reads return
+// early with what they have once ready reports that the next character is not
+// buffered, rather than waiting for more input.
+type runeReader struct {
+	next  func() (rune, error)
+	ready func() bool
+	// pending is the rest of a character that did not fit in the last read
+	pending []byte
+}
+
+func (c *runeReader) Read(p []byte) (int, error) {
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	for n < len(p) {
+		if n > 0 && !c.ready() {
+			break
+		}
+
+		char, err := c.next()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+
+		var encoded [utf8.UTFMax]byte
+		size := utf8.EncodeRune(encoded[:], char)
+		copied := copy(p[n:], encoded[:size])
+		c.pending = append(c.pending, encoded[copied:size]...)
+		n += copied
+	}
+	return n, nil
+}
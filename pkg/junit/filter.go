@@ -0,0 +1,114 @@
+package junit
+
+import (
+	"regexp"
+)
+
+// Filter selects testcases by matching their classname.name id against an
+// optional include pattern and any number of exclude patterns
+type Filter struct {
+	include  *regexp.Regexp
+	excludes []*regexp.Regexp
+}
+
+// NewFilter compiles the include and exclude regular expressions, either of
+// which may be empty
+func NewFilter(include string, excludes []string) (Filter, error) {
+	var filter Filter
+	if include != "" {
+		pattern, err := regexp.Compile(include)
+		if err != nil {
+			return filter, err
+		}
+		filter.include = pattern
+	}
+
+	for _, exclude := range excludes {
+		pattern, err := regexp.Compile(exclude)
+		if err != nil {
+			return filter, err
+		}
+		filter.excludes = append(filter.excludes, pattern)
+	}
+
+	return filter, nil
+}
+
+// Active reports whether the filter excludes any testcases
+func (f Filter) Active() bool {
+	return f.include != nil || len(f.excludes) > 0
+}
+
+// Match reports whether the testcase should be kept
+func (f Filter) Match(testcase Case) bool {
+	id := testcase.Id()
+	if f.include != nil && !f.include.MatchString(id) {
+		return false
+	}
+	for _, exclude := range f.excludes {
+		if exclude.MatchString(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterSuites keeps only the matching testcases, recomputing each suite's
+// counts and dropping empty suites
+func FilterSuites(suites []Suite, filter Filter) []Suite {
+	if !filter.Active() {
+		return suites
+	}
+
+	var filtered []Suite
+	for _, suite := range suites {
+		var cases []Case
+		for _, testcase := range suite.Cases {
+			if filter.Match(testcase) {
+				cases = append(cases, testcase)
+			}
+		}
+
+		if len(cases) == 0 {
+			continue
+		}
+
+		suite.Cases = cases
+		suite.Count()
+		filtered = append(filtered, suite)
+	}
+	return filtered
+}
+
+// FilterReport keeps only the matching testcases, recomputing the summary
+func FilterReport(report Report, filter Filter) Report {
+	if !filter.Active() {
+		return report
+	}
+
+	filtered := Report{ParseErrors: report.ParseErrors, MissingPaths: report.MissingPaths, Unparsed: report.Unparsed, MissingSuites: report.MissingSuites, Coverage: report.Coverage, Benchmarks: report.Benchmarks, Body: report.Body}
+	for _, suite := range FilterSuites(report.Suites, filter) {
+		filtered.Add(suite)
+	}
+	for _, testcase := range report.FlakyTests {
+		if filter.Match(testcase) {
+			filtered.FlakyTests = append(filtered.FlakyTests, testcase)
+		}
+	}
+	for _, testcase := range report.QuarantinedTests {
+		if filter.Match(testcase) {
+			filtered.QuarantinedTests = append(filtered.QuarantinedTests, testcase)
+		}
+	}
+	for _, testcase := range report.SlowTests {
+		if filter.Match(testcase) {
+			filtered.SlowTests = append(filtered.SlowTests, testcase)
+		}
+	}
+	for _, slowdown := range report.SlowerTests {
+		if filter.Match(slowdown.Case) {
+			filtered.SlowerTests = append(filtered.SlowerTests, slowdown)
+		}
+	}
+	return filtered
+}
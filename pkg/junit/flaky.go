@@ -0,0 +1,69 @@
+package junit
+
+// MarkFlaky finds testcases that appear more than once with both passing and
+// failing results, such as across shards or retried runs, and marks their
+// failures as flaky so they no longer count against the run. Each flaky
+// testcase is listed once in the report with its last failure.
+func MarkFlaky(report Report) Report {
+	passed := map[string]bool{}
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			if testcase.Status == StatusPassed {
+				passed[testcase.Id()] = true
+			}
+		}
+	}
+
+	marked, flaky := reclassify(report, StatusFlaky, func(testcase Case) bool {
+		return passed[testcase.Id()]
+	})
+
+	var ids []string
+	last := map[string]Case{}
+	for _, testcase := range flaky {
+		if _, ok := last[testcase.Id()]; !ok {
+			ids = append(ids, testcase.Id())
+		}
+		last[testcase.Id()] = testcase
+	}
+	for _, id := range ids {
+		marked.FlakyTests = append(marked.FlakyTests, last[id])
+	}
+	return marked
+}
+
+// reclassify gives the failing testcases that match a new status, which no
+// longer counts as a failure, and returns the report along with the
+// testcases that were changed. The suites' own counts are adjusted rather
+// than recomputed, since they may count testcases missing from the report.
+func reclassify(report Report, status Status, match func(Case) bool) (Report, []Case) {
+	var changed []Case
+	reclassified := report
+	reclassified.Summary = Summary{}
+	reclassified.Suites = nil
+	for _, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for i, testcase := range suite.Cases {
+			if testcase.Failed() && match(testcase) {
+				if testcase.Status == StatusError {
+					suite.Errors--
+				} else {
+					suite.Failures--
+				}
+				switch status {
+				case StatusFlaky:
+					suite.Flaky++
+				case StatusQuarantined:
+					suite.Quarantined++
+				}
+
+				testcase.Status = status
+				changed = append(changed, testcase)
+			}
+			cases[i] = testcase
+		}
+		suite.Cases = cases
+		reclassified.Add(suite)
+	}
+	return reclassified, changed
+}
@@ -0,0 +1,68 @@
+package junit
+
+// ReportGroup is the results of the suites of a report with the same name,
+// or of the same labeled build
+type ReportGroup struct {
+	Name   string
+	Report Report
+}
+
+// GroupReport splits the report into the results of each labeled build when
+// its suites have at least two sets of labels, and of each suite name
+// otherwise, in the order they first appear. The flaky, quarantined, slow, and
+// slower than baseline testcases go with the group of their suite, while the
+// notes and sections for the whole run, such as parse errors and coverage,
+// go with the first group.
+func GroupReport(report Report) []ReportGroup {
+	byLabel := len(report.Labels()) >= 2
+	index := map[string]int{}
+	var groups []ReportGroup
+	groupOf := map[string]int{}
+	for _, suite := range report.Suites {
+		name := suite.Name
+		if byLabel {
+			name = suite.Label()
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, ReportGroup{Name: name})
+		}
+		groups[i].Report.Add(suite)
+		for _, testcase := range suite.Cases {
+			if _, ok := groupOf[testcase.Id()]; !ok {
+				groupOf[testcase.Id()] = i
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	for _, testcase := range report.FlakyTests {
+		group := &groups[groupOf[testcase.Id()]].Report
+		group.FlakyTests = append(group.FlakyTests, testcase)
+	}
+	for _, testcase := range report.QuarantinedTests {
+		group := &groups[groupOf[testcase.Id()]].Report
+		group.QuarantinedTests = append(group.QuarantinedTests, testcase)
+	}
+	for _, testcase := range report.SlowTests {
+		group := &groups[groupOf[testcase.Id()]].Report
+		group.SlowTests = append(group.SlowTests, testcase)
+	}
+	for _, slowdown := range report.SlowerTests {
+		group := &groups[groupOf[slowdown.Id()]].Report
+		group.SlowerTests = append(group.SlowerTests, slowdown)
+	}
+
+	first := &groups[0].Report
+	first.ParseErrors = report.ParseErrors
+	first.MissingPaths = report.MissingPaths
+	first.Unparsed = report.Unparsed
+	first.MissingSuites = report.MissingSuites
+	first.Coverage = report.Coverage
+	first.Benchmarks = report.Benchmarks
+	return groups
+}
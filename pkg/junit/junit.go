@@ -0,0 +1,363 @@
+// Package junit parses junit and xunit xml reports into test results
+package junit
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xmlTestsuite is a testsuite element, used when writing reports
+type xmlTestsuite struct {
+	XMLName    xml.Name      `xml:"testsuite"`
+	Name       string        `xml:"name,attr"`
+	Tests      int           `xml:"tests,attr"`
+	Failures   int           `xml:"failures,attr"`
+	Errors     int           `xml:"errors,attr"`
+	Skipped    int           `xml:"skipped,attr"`
+	Assertions int           `xml:"assertions,attr,omitempty"`
+	Time       string        `xml:"time,attr,omitempty"`
+	Timestamp  string        `xml:"timestamp,attr,omitempty"`
+	Hostname   string        `xml:"hostname,attr,omitempty"`
+	Testcases  []xmlTestcase `xml:"testcase"`
+}
+
+// xmlTestcase is a testcase element, whose time may be fractional
+type xmlTestcase struct {
+	XMLName    xml.Name      `xml:"testcase"`
+	Classname  string        `xml:"classname,attr"`
+	Name       string        `xml:"name,attr"`
+	Time       string        `xml:"time,attr"`
+	Assertions string        `xml:"assertions,attr,omitempty"`
+	Status     string        `xml:"status,attr,omitempty"`
+	Failure    *xmlFailure   `xml:"failure"`
+	Error      *xmlFailure   `xml:"error"`
+	Skipped    *xmlSkipped   `xml:"skipped"`
+	SystemOut  string        `xml:"system-out,omitempty"`
+	SystemErr  string        `xml:"system-err,omitempty"`
+	Properties []xmlProperty `xml:"properties>property,omitempty"`
+}
+
+// xmlProperty is a property of a testcase
+type xmlProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// xmlFailure is a failure or error element, whose message is usually its
+// text, but is sometimes only given as an attribute
+type xmlFailure struct {
+	Type        string `xml:"type,attr,omitempty"`
+	MessageAttr string `xml:"message,attr,omitempty"`
+	Message     string `xml:",chardata"`
+}
+
+func (f xmlFailure) failure() Failure {
+	message := f.Message
+	if strings.TrimSpace(message) == "" {
+		message = f.MessageAttr
+	}
+	return Failure{Type: f.Type, Message: message}
+}
+
+// xmlSkipped is a skipped element, whose reason is usually its message
+// attribute, but is sometimes only given as its text
+type xmlSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func (s xmlSkipped) message() string {
+	if s.Message != "" {
+		return s.Message
+	}
+	return strings.TrimSpace(s.Text)
+}
+
+// statusAttributes are the values of the status attribute that some
+// frameworks, such as bazel, set on testcases rather than adding a child
+// element
+var statusAttributes = map[string]Status{
+	"notrun":   StatusSkipped,
+	"skipped":  StatusSkipped,
+	"disabled": StatusSkipped,
+	"ignored":  StatusSkipped,
+	"failed":   StatusFailed,
+	"failure":  StatusFailed,
+	"error":    StatusError,
+	"errored":  StatusError,
+}
+
+// newCase converts a decoded testcase element into a testcase, whose status
+// attribute is used when no child element marks it as failed or skipped
+func newCase(element xmlTestcase) Case {
+	testcase := Case{Classname: element.Classname, Name: element.Name, Status: StatusPassed}
+	testcase.Time, _ = strconv.ParseFloat(element.Time, 64)
+	testcase.Assertions, _ = strconv.Atoi(element.Assertions)
+	switch {
+	case element.Error != nil:
+		testcase.Status = StatusError
+		testcase.Failure = element.Error.failure()
+	case element.Failure != nil:
+		testcase.Failure = element.Failure.failure()
+		if testcase.Failure.Message != "" {
+			testcase.Status = StatusFailed
+		}
+	case element.Skipped != nil:
+		testcase.Status = StatusSkipped
+		testcase.SkipMessage = element.Skipped.message()
+	}
+	if status, ok := statusAttributes[strings.ToLower(element.Status)]; ok && testcase.Status == StatusPassed {
+		testcase.Status = status
+	}
+	testcase.Attachments = attachments(element, testcase.Failure.Message)
+	testcase.Owners = propertyOwners(element.Properties)
+	return testcase
+}
+
+// osFS opens paths on the operating system's filesystem as given, unlike
+// os.DirFS, so that absolute and parent paths may be used
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+// GetFiles returns the xml reports among the paths, reading the reports in
+// directories but not their subdirectories, and defaulting to the current
+// directory. Paths use the separators of the operating system, so backslashes
+// and drive letters work on windows.
+func GetFiles(args []string) ([]string, error) {
+	return FindFiles(osFS{}, args)
+}
+
+// FindFiles returns the xml reports among the paths within fsys, such as an
+// embedded fixture directory or a zip archive, reading the reports in
+// directories but not their subdirectories, and defaulting to the current
+// directory. Paths that do not exist are read as glob patterns, such as
+// reports/*/junit.xml, when they match anything.
+func FindFiles(fsys fs.FS, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return getFilesFromPath(fsys, ".")
+	}
+
+	var files []string
+	for _, name := range paths {
+		name = cleanPath(fsys, name)
+		f, err := fs.Stat(fsys, name)
+		if err != nil {
+			if matches, _ := glob(fsys, name); len(matches) > 0 {
+				matched, err := FindFiles(fsys, matches)
+				files = append(files, matched...)
+				if err != nil {
+					return files, err
+				}
+				continue
+			}
+			return files, err
+		}
+		if f.IsDir() {
+			filesInPath, err := getFilesFromPath(fsys, name)
+			if err != nil {
+				return files, err
+			}
+			files = append(files, filesInPath...)
+		} else if isReport(f.Name()) {
+			files = append(files, name)
+		}
+	}
+
+	return files, nil
+}
+
+func getFilesFromPath(fsys fs.FS, dir string) ([]string, error) {
+	var files []string
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return files, err
+	}
+
+	for _, f := range entries {
+		if f.IsDir() {
+			continue
+		}
+		if isReport(f.Name()) {
+			files = append(files, joinPath(fsys, dir, f.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// glob returns the paths within fsys that match the pattern, or nothing when
+// it is not a pattern, using the separators of the operating system for its
+// own filesystem like cleanPath
+func glob(fsys fs.FS, pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return nil, nil
+	}
+	if _, ok := fsys.(osFS); ok {
+		return filepath.Glob(pattern)
+	}
+	return fs.Glob(fsys, pattern)
+}
+
+// isReport reports whether the file is named like an xml report, ignoring
+// case as Windows does
+func isReport(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".xml")
+}
+
+// cleanPath cleans a path within fsys, using the separators of the operating
+// system for its own filesystem and slashes for any other
+func cleanPath(fsys fs.FS, name string) string {
+	if _, ok := fsys.(osFS); ok {
+		return filepath.Clean(name)
+	}
+	return path.Clean(name)
+}
+
+// joinPath joins a directory and a name within it, like cleanPath
+func joinPath(fsys fs.FS, dir string, name string) string {
+	if _, ok := fsys.(osFS); ok {
+		return filepath.Join(dir, name)
+	}
+	return path.Join(dir, name)
+}
+
+// Parse reads a report with either a testsuite root element, or a testsuites
+// root element wrapping any number of suites, which may start with a utf-8 or
+// utf-16 byte order mark. Testcases are decoded one at a time as they are
+// read, so memory use depends on the size of the results rather than the size
+// of the report. When the report cannot be read, such as when it is
+// truncated, the suites read so far are returned along with the error.
+func Parse(r io.Reader) (*Report, error) {
+	return decode(xml.NewDecoder(decodeBOM(r)))
+}
+
+// decode reads the suites of a report from the decoder
+func decode(decoder *xml.Decoder) (*Report, error) {
+	decoder.CharsetReader = charsetReader
+	report := &Report{}
+
+	// suites may be nested, in which case each is reported separately
+	var suites []*Suite
+	var err error
+tokens:
+	for {
+		var token xml.Token
+		token, err = decoder.Token()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch {
+			case element.Name.Local == "testsuites":
+			case element.Name.Local == "testsuite":
+				suites = append(suites, newSuite(element))
+			case element.Name.Local == "testcase" && len(suites) > 0:
+				var testcase xmlTestcase
+				if err = decoder.DecodeElement(&testcase, &element); err != nil {
+					break tokens
+				}
+				suite := suites[len(suites)-1]
+				suite.Cases = append(suite.Cases, newCase(testcase))
+			default:
+				if err = decoder.Skip(); err != nil {
+					break tokens
+				}
+			}
+		case xml.EndElement:
+			if element.Name.Local == "testsuite" && len(suites) > 0 {
+				report.Add(finishSuite(suites[len(suites)-1]))
+				suites = suites[:len(suites)-1]
+			}
+		}
+	}
+
+	// keep the testcases of suites that were cut off
+	for i := len(suites) - 1; i >= 0; i-- {
+		report.Add(finishSuite(suites[i]))
+	}
+	return report, err
+}
+
+// finishSuite recounts a suite from its testcases when the counts it reports
+// are missing or disagree with them, such as when a framework leaves out the
+// failures attribute, marks testcases with their status attribute, or counts
+// the testcases of nested suites, and otherwise totals the assertions of the
+// testcases of a suite that does not report its own
+func finishSuite(suite *Suite) Suite {
+	counted := *suite
+	counted.Count()
+	reported := suite.Summary
+	if reported.Tests != counted.Tests || reported.Failures != counted.Failures || reported.Errors != counted.Errors || reported.Skipped != counted.Skipped {
+		return counted
+	}
+	if suite.Assertions == 0 {
+		suite.Assertions = caseAssertions(suite.Cases)
+	}
+	return *suite
+}
+
+// newSuite reads the attributes of a testsuite element
+func newSuite(element xml.StartElement) *Suite {
+	testsuite := &Suite{}
+	for _, attr := range element.Attr {
+		switch attr.Name.Local {
+		case "name":
+			testsuite.Name = attr.Value
+		case "tests":
+			testsuite.Tests, _ = strconv.Atoi(attr.Value)
+		case "failures":
+			testsuite.Failures, _ = strconv.Atoi(attr.Value)
+		case "errors":
+			testsuite.Errors, _ = strconv.Atoi(attr.Value)
+		case "skipped":
+			testsuite.Skipped, _ = strconv.Atoi(attr.Value)
+		case "assertions":
+			testsuite.Assertions, _ = strconv.Atoi(attr.Value)
+		case "time":
+			testsuite.Time, _ = strconv.ParseFloat(attr.Value, 64)
+		case "timestamp":
+			testsuite.Timestamp = attr.Value
+		case "hostname":
+			testsuite.Hostname = attr.Value
+		}
+	}
+	return testsuite
+}
+
+// ParseFile parses the report at the path
+func ParseFile(file string) (*Report, error) {
+	return Parser{}.ParseFile(file)
+}
+
+// ParseFS parses the report at the path within fsys
+func ParseFS(fsys fs.FS, file string) (*Report, error) {
+	return Parser{FS: fsys}.ParseFile(file)
+}
+
+// ParseFiles parses the files using up to concurrency workers at a time, as
+// described by Parser.ParseFiles
+func ParseFiles(ctx context.Context, files []string, concurrency int, progress func(file string, duration time.Duration, done int)) (Report, error) {
+	return Parser{Concurrency: concurrency, Progress: progress}.ParseFiles(ctx, files)
+}
+
+// ParseFilesFS parses the files within fsys like ParseFiles
+func ParseFilesFS(ctx context.Context, fsys fs.FS, files []string, concurrency int, progress func(file string, duration time.Duration, done int)) (Report, error) {
+	return Parser{FS: fsys, Concurrency: concurrency, Progress: progress}.ParseFiles(ctx, files)
+}
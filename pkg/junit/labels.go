@@ -0,0 +1,110 @@
+package junit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseLabels parses labels written as comma separated key=value pairs, such
+// as os=linux,go=1.22
+func ParseLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid label, expected key=value: %s", pair)
+		}
+		labels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return labels, nil
+}
+
+// Label describes the labels of the suite as key=value pairs ordered by key,
+// or nothing when it has none
+func (s Suite) Label() string {
+	keys := make([]string, 0, len(s.Labels))
+	for key := range s.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + s.Labels[key]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// LabelReport adds the labels to every suite of the report, such as the os
+// and versions of the matrix build the tests were run in, keeping any labels
+// the suites already have
+func LabelReport(report Report, labels map[string]string) Report {
+	if len(labels) == 0 {
+		return report
+	}
+
+	labeled := report
+	labeled.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		merged := map[string]string{}
+		for key, value := range labels {
+			merged[key] = value
+		}
+		for key, value := range suite.Labels {
+			merged[key] = value
+		}
+		suite.Labels = merged
+		labeled.Suites[i] = suite
+	}
+	return labeled
+}
+
+// Combine joins the results of several runs, such as the labeled results of
+// each build in a matrix, into a single report. Their coverage is added up
+// without the baselines it was compared to.
+func Combine(reports ...Report) Report {
+	var combined Report
+	var coverages []Coverage
+	for _, report := range reports {
+		if report.Coverage != nil {
+			coverages = append(coverages, *report.Coverage)
+		}
+		for _, suite := range report.Suites {
+			combined.Add(suite)
+		}
+		combined.FlakyTests = append(combined.FlakyTests, report.FlakyTests...)
+		combined.QuarantinedTests = append(combined.QuarantinedTests, report.QuarantinedTests...)
+		combined.ParseErrors = append(combined.ParseErrors, report.ParseErrors...)
+		combined.MissingPaths = append(combined.MissingPaths, report.MissingPaths...)
+		combined.Unparsed = append(combined.Unparsed, report.Unparsed...)
+		combined.MissingSuites = append(combined.MissingSuites, report.MissingSuites...)
+		combined.SlowTests = append(combined.SlowTests, report.SlowTests...)
+		combined.SlowerTests = append(combined.SlowerTests, report.SlowerTests...)
+		combined.Benchmarks = append(combined.Benchmarks, report.Benchmarks...)
+	}
+	if len(coverages) > 0 {
+		coverage := CombineCoverage(coverages...)
+		combined.Coverage = &coverage
+	}
+	return combined
+}
+
+// Labels returns the distinct labels of the suites as described by Label, in
+// the order they first appear
+func (r Report) Labels() []string {
+	var labels []string
+	seen := map[string]bool{}
+	for _, suite := range r.Suites {
+		label := suite.Label()
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,44 @@
+package junit
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+)
+
+// ParseLenient reads a report like Parse, while recovering from common
+// mistakes in reports that would otherwise stop the decoder. Characters that
+// are not allowed in xml, such as raw control characters, are dropped, and
+// invalid utf-8 is replaced. Bare ampersands and undeclared entities are kept
+// as written, and html entities such as &nbsp; are decoded.
+func ParseLenient(r io.Reader) (*Report, error) {
+	decoder := xml.NewDecoder(newLenientReader(decodeBOM(r)))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	return decode(decoder)
+}
+
+// newLenientReader drops the characters that are not allowed in xml, and
+// replaces invalid utf-8 with the unicode replacement character
+func newLenientReader(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	return &runeReader{
+		next: func() (rune, error) {
+			for {
+				char, _, err := buffered.ReadRune()
+				if err != nil || isXMLChar(char) {
+					return char, err
+				}
+			}
+		},
+		ready: func() bool { return buffered.Buffered() > 0 },
+	}
+}
+
+// isXMLChar reports whether the character is allowed in xml documents
+func isXMLChar(char rune) bool {
+	return char == '\t' || char == '\n' || char == '\r' ||
+		char >= 0x20 && char <= 0xD7FF ||
+		char >= 0xE000 && char <= 0xFFFD ||
+		char >= 0x10000 && char <= 0x10FFFF
+}
@@ -0,0 +1,189 @@
+package junit
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Merge combines suites with the same name and labels, such as those from
+// shards or retries, into a single suite. Suites are ordered by their timestamps, with
+// suites that have none kept in the order given after those that do. When a
+// testcase appears more than once, its last occurrence is kept as the final
+// status, so retries win over the runs they retry, as do reports listed after
+// others when there are no timestamps. Suites that appear once are kept as
+// they are.
+func Merge(suites []Suite) []Suite {
+	suites = chronological(suites)
+
+	// suites from different builds of a matrix are kept apart
+	var keys []string
+	groups := map[string][]Suite{}
+	for _, suite := range suites {
+		key := suite.Name + "\x00" + suite.Label()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], suite)
+	}
+
+	var merged []Suite
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) == 1 {
+			merged = append(merged, group[0])
+			continue
+		}
+
+		suite := group[0]
+		suite.Cases = nil
+		suite.Time = 0
+		suite.Assertions = 0
+		positions := map[string]int{}
+		for _, shard := range group {
+			suite.Time += shard.Time
+			suite.Assertions += shard.Assertions
+			for _, testcase := range shard.Cases {
+				if position, ok := positions[testcase.Id()]; ok {
+					suite.Cases[position] = testcase
+					continue
+				}
+				positions[testcase.Id()] = len(suite.Cases)
+				suite.Cases = append(suite.Cases, testcase)
+			}
+		}
+
+		suite.Count()
+		merged = append(merged, suite)
+	}
+	return merged
+}
+
+// MergeShards combines suites with the same name and labels whose testcases
+// do not overlap, such as a suite split across the shards of a run, into one
+// suite in place of the first with the summed counts and time of each shard
+// and all of their testcases. Suites whose testcases overlap, such as
+// retries, are kept apart for MarkFlaky and Merge to reconcile.
+func MergeShards(report Report) Report {
+	merged := report
+	merged.Suites = nil
+	positions := map[string]int{}
+	ids := map[string]map[string]bool{}
+	for _, suite := range report.Suites {
+		key := suite.Name + "\x00" + suite.Label()
+		position, ok := positions[key]
+		if !ok || overlaps(ids[key], suite.Cases) {
+			if !ok {
+				positions[key] = len(merged.Suites)
+				ids[key] = caseIds(suite.Cases)
+			}
+			merged.Suites = append(merged.Suites, suite)
+			continue
+		}
+
+		target := &merged.Suites[position]
+		target.Summary.Add(suite)
+		target.Time += suite.Time
+		target.Cases = append(append([]Case{}, target.Cases...), suite.Cases...)
+		if started := suite.Started(); !started.IsZero() && (target.Started().IsZero() || started.Before(target.Started())) {
+			target.Timestamp = suite.Timestamp
+		}
+		for id := range caseIds(suite.Cases) {
+			ids[key][id] = true
+		}
+	}
+	return merged
+}
+
+func caseIds(cases []Case) map[string]bool {
+	ids := map[string]bool{}
+	for _, testcase := range cases {
+		ids[testcase.Id()] = true
+	}
+	return ids
+}
+
+func overlaps(ids map[string]bool, cases []Case) bool {
+	for _, testcase := range cases {
+		if ids[testcase.Id()] {
+			return true
+		}
+	}
+	return false
+}
+
+// chronological returns a copy of the suites in the order they started, with
+// those without a timestamp last
+func chronological(suites []Suite) []Suite {
+	sorted := make([]Suite, len(suites))
+	copy(sorted, suites)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Started(), sorted[j].Started()
+		return !a.IsZero() && (b.IsZero() || a.Before(b))
+	})
+	return sorted
+}
+
+// WriteXML writes the suites as a junit xml report
+func WriteXML(w io.Writer, suites []Suite) error {
+	report := struct {
+		XMLName    xml.Name       `xml:"testsuites"`
+		Testsuites []xmlTestsuite `xml:"testsuite"`
+	}{}
+	for _, suite := range suites {
+		element := xmlTestsuite{
+			Name:       suite.Name,
+			Tests:      suite.Tests,
+			Failures:   suite.Failures,
+			Errors:     suite.Errors,
+			Skipped:    suite.Skipped,
+			Assertions: suite.Assertions,
+			Time:       formatSeconds(suite.Time),
+			Timestamp:  suite.Timestamp,
+			Hostname:   suite.Hostname,
+		}
+		for _, testcase := range suite.Cases {
+			testcaseElement := xmlTestcase{Classname: testcase.Classname, Name: testcase.Name, Time: formatSeconds(testcase.Time)}
+			if testcase.Assertions > 0 {
+				testcaseElement.Assertions = strconv.Itoa(testcase.Assertions)
+			}
+			switch testcase.Status {
+			case StatusError:
+				testcaseElement.Error = &xmlFailure{Type: testcase.Failure.Type, Message: testcase.Failure.Message}
+			case StatusSkipped:
+				testcaseElement.Skipped = &xmlSkipped{Message: testcase.SkipMessage}
+			case StatusFailed:
+				testcaseElement.Failure = &xmlFailure{Type: testcase.Failure.Type, Message: testcase.Failure.Message}
+			default:
+				if testcase.Failure.Message != "" {
+					testcaseElement.Failure = &xmlFailure{Type: testcase.Failure.Type, Message: testcase.Failure.Message}
+				}
+			}
+			if len(testcase.Owners) > 0 {
+				testcaseElement.Properties = append(testcaseElement.Properties, xmlProperty{Name: "owner", Value: strings.Join(testcase.Owners, ",")})
+			}
+			for _, attachment := range testcase.Attachments {
+				testcaseElement.Properties = append(testcaseElement.Properties, xmlProperty{Name: "attachment", Value: attachment.Path})
+			}
+			element.Testcases = append(element.Testcases, testcaseElement)
+		}
+		report.Testsuites = append(report.Testsuites, element)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
@@ -0,0 +1,165 @@
+package junit
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameOptions configures how PrettifyNames shortens the names testcases are
+// rendered with
+type NameOptions struct {
+	// StripPrefixes are removed from the start of names and classnames, such
+	// as com.example.
+	StripPrefixes []string
+	// StripPackages shows only the last part of dotted or slashed classnames
+	StripPackages bool
+	// StripTestPrefix removes the Test or test_ that frameworks require names
+	// to start with
+	StripTestPrefix bool
+	// Humanize splits camel case and snake case names into lowercase words
+	Humanize bool
+	// MaxParameters is the most characters of the parameters of a
+	// parameterized name to show, or 0 to show them in full
+	MaxParameters int
+}
+
+// Active reports whether the options change any names
+func (o NameOptions) Active() bool {
+	return len(o.StripPrefixes) > 0 || o.StripPackages || o.StripTestPrefix || o.Humanize || o.MaxParameters > 0
+}
+
+var (
+	// parametersPattern matches the parameters of a parameterized name, such
+	// as test_login[chrome-1920x1080] or testAdd(int, int)
+	parametersPattern = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+	testPrefixPattern = regexp.MustCompile(`^(?:[Tt]est(?:[_\s]+|([A-Z0-9])))`)
+	// camelPattern matches where a camel case name starts a new word, such as
+	// between the r and C of UserCanLogin, or the L and P of HTMLParser
+	camelPattern    = regexp.MustCompile(`([\p{Ll}\d])(\p{Lu})|(\p{Lu})(\p{Lu}\p{Ll})`)
+	separatePattern = regexp.MustCompile(`[_\s]+`)
+)
+
+// PrettifyNames sets the names the testcases of the report are rendered with,
+// leaving their names and ids as reported so that filters, flaky detection,
+// and history still match them
+func PrettifyNames(report Report, options NameOptions) Report {
+	if !options.Active() {
+		return report
+	}
+
+	prettify := func(cases []Case) []Case {
+		if cases == nil {
+			return nil
+		}
+		prettified := make([]Case, len(cases))
+		for i, testcase := range cases {
+			testcase.DisplayName = options.name(testcase.Name)
+			testcase.DisplayClassname = options.classname(testcase.Classname)
+			prettified[i] = testcase
+		}
+		return prettified
+	}
+
+	prettified := report
+	prettified.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		suite.Cases = prettify(suite.Cases)
+		prettified.Suites[i] = suite
+	}
+	prettified.FlakyTests = prettify(report.FlakyTests)
+	prettified.QuarantinedTests = prettify(report.QuarantinedTests)
+	prettified.SlowTests = prettify(report.SlowTests)
+	return prettified
+}
+
+func (o NameOptions) name(name string) string {
+	pretty := o.stripPrefixes(name)
+	if o.MaxParameters > 0 {
+		pretty = parametersPattern.ReplaceAllStringFunc(pretty, func(parameters string) string {
+			inner := parameters[1 : len(parameters)-1]
+			if utf8.RuneCountInString(inner) <= o.MaxParameters {
+				return parameters
+			}
+			return parameters[:1] + string([]rune(inner)[:o.MaxParameters]) + "…" + parameters[len(parameters)-1:]
+		})
+	}
+
+	// only the name is changed, leaving the parameters as reported
+	base, parameters := pretty, ""
+	if start := parametersPattern.FindStringIndex(pretty); start != nil {
+		base, parameters = pretty[:start[0]], pretty[start[0]:]
+	}
+	if o.StripTestPrefix {
+		if stripped := testPrefixPattern.ReplaceAllString(base, "$1"); strings.TrimSpace(stripped) != "" {
+			base = stripped
+		}
+	}
+	if o.Humanize {
+		segments := strings.Split(base, "/")
+		for i, segment := range segments {
+			segments[i] = humanize(segment)
+		}
+		base = strings.Join(segments, "/")
+	}
+	if pretty = base + parameters; pretty == name {
+		return ""
+	}
+	return pretty
+}
+
+func (o NameOptions) classname(classname string) string {
+	pretty := o.stripPrefixes(classname)
+	if o.StripPackages {
+		if i := strings.LastIndexAny(pretty, "./"); i >= 0 && i < len(pretty)-1 {
+			pretty = pretty[i+1:]
+		}
+	}
+	if pretty == classname {
+		return ""
+	}
+	return pretty
+}
+
+// stripPrefixes removes the first matching prefix, unless that leaves
+// nothing
+func (o NameOptions) stripPrefixes(name string) string {
+	for _, prefix := range o.StripPrefixes {
+		if prefix != "" && strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return name[len(prefix):]
+		}
+	}
+	return name
+}
+
+// humanize splits a camel case or snake case name into words, which are
+// lowercased unless they are acronyms, such as "UserCanParseHTML" becoming
+// "user can parse HTML"
+func humanize(name string) string {
+	spaced := camelPattern.ReplaceAllString(name, "$1$3 $2$4")
+	spaced = camelPattern.ReplaceAllString(spaced, "$1$3 $2$4")
+	words := separatePattern.Split(strings.TrimSpace(spaced), -1)
+	for i, word := range words {
+		if !acronym(word) {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	if humanized := strings.Join(words, " "); humanized != "" {
+		return humanized
+	}
+	return name
+}
+
+func acronym(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
@@ -0,0 +1,160 @@
+package junit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ownerProperties are the testcase properties that name its owners
+var ownerProperties = []string{"owner", "owners", "team"}
+
+// propertyOwners are the owners named by the properties of a testcase, which
+// may list several separated by commas or spaces
+func propertyOwners(properties []xmlProperty) []string {
+	for _, property := range properties {
+		for _, name := range ownerProperties {
+			if strings.EqualFold(property.Name, name) {
+				if owners := splitOwners(property.Value); len(owners) > 0 {
+					return owners
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func splitOwners(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// Owners assigns owners to testcases that do not name their own
+type Owners struct {
+	rules []ownerRule
+}
+
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// ReadOwners reads an owners file with a pattern followed by one or more
+// owners on each line, such as com.example.billing.* @org/billing. Patterns
+// match the id of a testcase in the form classname.name, or the file it
+// failed in, where * matches any characters. As with CODEOWNERS, the last
+// matching line wins. Blank lines and lines starting with # are ignored.
+func ReadOwners(r io.Reader) (Owners, error) {
+	var owners Owners
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return owners, fmt.Errorf("line %d: expected a pattern followed by owners", line)
+		}
+		compiled, err := compileGlob(fields[0])
+		if err != nil {
+			return owners, fmt.Errorf("line %d: %s", line, err)
+		}
+		owners.rules = append(owners.rules, ownerRule{pattern: compiled, owners: fields[1:]})
+	}
+	return owners, scanner.Err()
+}
+
+// Match returns the owners of the testcase, from the last line of the owners
+// file that matches it
+func (o Owners) Match(testcase Case) []string {
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		rule := o.rules[i]
+		if rule.pattern.MatchString(testcase.Id()) || (testcase.Location != nil && rule.pattern.MatchString(testcase.Location.File)) {
+			return rule.owners
+		}
+	}
+	return nil
+}
+
+// AssignOwners sets the owners of the testcases of the report that do not
+// name their own in their properties from the owners file
+func AssignOwners(report Report, owners Owners) Report {
+	if len(owners.rules) == 0 {
+		return report
+	}
+
+	assigned := report
+	assigned.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if len(testcase.Owners) == 0 {
+				testcase.Owners = owners.Match(testcase)
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		assigned.Suites[i] = suite
+	}
+	return assigned
+}
+
+// OwnedFailures are the failing testcases of an owner, or of no owner when
+// Owner is empty
+type OwnedFailures struct {
+	Owner string `json:"owner"`
+	Cases []Case `json:"testcases"`
+}
+
+// FailuresByOwner groups the failing testcases of the report by their
+// owners, with the owners with the most failures first and the failures
+// without an owner last. A testcase with several owners is grouped under
+// each. Nothing is returned when no failure has an owner.
+func FailuresByOwner(report Report) []OwnedFailures {
+	index := map[string]int{}
+	var groups []OwnedFailures
+	owned := false
+	add := func(owner string, testcase Case) {
+		if i, ok := index[owner]; ok {
+			groups[i].Cases = append(groups[i].Cases, testcase)
+			return
+		}
+		index[owner] = len(groups)
+		groups = append(groups, OwnedFailures{Owner: owner, Cases: []Case{testcase}})
+	}
+
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			if !testcase.Failed() {
+				continue
+			}
+			if len(testcase.Owners) == 0 {
+				add("", testcase)
+				continue
+			}
+			owned = true
+			for _, owner := range testcase.Owners {
+				add(owner, testcase)
+			}
+		}
+	}
+	if !owned {
+		return nil
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if (groups[i].Owner == "") != (groups[j].Owner == "") {
+			return groups[j].Owner == ""
+		}
+		return len(groups[i].Cases) > len(groups[j].Cases)
+	})
+	return groups
+}
@@ -0,0 +1,153 @@
+package junit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Parser parses reports with its options, which may all be left unset
+type Parser struct {
+	// FS is the filesystem reports are read from, defaulting to the
+	// operating system's
+	FS fs.FS
+	// Concurrency is how many reports are parsed at a time, defaulting to 1
+	Concurrency int
+	// MaxSize is the most bytes read from each report, or unlimited when 0.
+	// Larger reports are truncated, keeping the testcases read before the
+	// limit, and returned with an error.
+	MaxSize int64
+	// Lenient recovers from invalid characters and entities in reports, as
+	// ParseLenient does
+	Lenient bool
+	// Progress is called after each file is parsed with how long the file
+	// took and how many files have been parsed so far, one call at a time
+	Progress func(file string, duration time.Duration, done int)
+}
+
+// ParseFile parses the report at the path
+func (p Parser) ParseFile(file string) (*Report, error) {
+	return p.parseFile(context.Background(), file)
+}
+
+// contextReader reads from r until the context is done, so that reports stop
+// being parsed partway through once it is
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// parseFile parses the report at the path, returning the suites read so far
+// along with the context's error once it is done
+func (p Parser) parseFile(ctx context.Context, file string) (*Report, error) {
+	fsys := p.FS
+	if fsys == nil {
+		fsys = osFS{}
+	}
+
+	xmlFile, err := fsys.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer xmlFile.Close()
+
+	parse := Parse
+	if p.Lenient {
+		parse = ParseLenient
+	}
+
+	var r io.Reader = bufio.NewReader(contextReader{ctx: ctx, r: xmlFile})
+	if p.MaxSize <= 0 {
+		return parse(r)
+	}
+
+	limited := &io.LimitedReader{R: r, N: p.MaxSize}
+	report, err := parse(limited)
+	if limited.N == 0 {
+		// the limit was reached, which truncated the report if anything is left
+		if n, _ := io.ReadFull(r, make([]byte, 1)); n > 0 {
+			return report, fmt.Errorf("report is larger than %d bytes, so only its first %d bytes were read", p.MaxSize, p.MaxSize)
+		}
+	}
+	return report, err
+}
+
+// ParseFiles parses the files, returning their suites in the same order as
+// the files. Files that cannot be parsed are listed in the report's
+// ParseErrors along with their error, while any suites read from them before
+// the error are kept. Once the context is done, files that have not started
+// parsing are skipped and those being parsed are stopped, and both are listed
+// in the report's Unparsed, returning the files parsed so far along with the
+// context's error.
+func (p Parser) ParseFiles(ctx context.Context, files []string) (Report, error) {
+	concurrency := p.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	parsed := make([]*Report, len(files))
+	errs := make([]error, len(files))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for worker := 0; worker < concurrency && worker < len(files); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+
+				start := time.Now()
+				parsed[i], errs[i] = p.parseFile(ctx, files[i])
+				duration := time.Since(start)
+
+				if p.Progress != nil {
+					mu.Lock()
+					done++
+					p.Progress(files[i], duration, done)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var report Report
+	for i := range files {
+		if errs[i] != nil && ctx.Err() != nil && errors.Is(errs[i], ctx.Err()) {
+			report.Unparsed = append(report.Unparsed, files[i])
+			continue
+		}
+		if errs[i] != nil {
+			report.ParseErrors = append(report.ParseErrors, ParseError{File: files[i], Message: errs[i].Error()})
+		}
+		if parsed[i] == nil {
+			continue
+		}
+		for _, suite := range parsed[i].Suites {
+			report.Add(suite)
+		}
+	}
+	return report, ctx.Err()
+}
@@ -0,0 +1,251 @@
+package junit
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PathMapping maps the classnames starting with Prefix, such as
+// com.example.api, to the directory of the repository their files are in,
+// such as api/src/test/java/com/example/api
+type PathMapping struct {
+	Prefix string
+	Path   string
+}
+
+// Locator finds where failures happened within the repository checked out at
+// Root
+type Locator struct {
+	Root string
+	// SourceUrl is a url that files in the repository are viewed under, such
+	// as https://github.com/owner/repo/blob/<commit>, that locations link to
+	// their line beneath when it is set
+	SourceUrl string
+	// FS is the repository, which the files that classnames and the partial
+	// paths of stack traces could be are looked up in. When it is nil,
+	// locations are only what stack traces name.
+	FS fs.FS
+	// Paths are tried before the built-in heuristics, for layouts they do not
+	// know about
+	Paths []PathMapping
+
+	exists  map[string]bool
+	roots   []string
+	mapping []PathMapping
+}
+
+// sourceRoots are the directories that jvm and python build tools keep
+// packages and modules beneath, which jvm stack traces and classnames are
+// relative to. Each is also looked for one directory down, as in the modules
+// of a multi-module build.
+var sourceRoots = []string{"src/test/java", "src/main/java", "src/test/kotlin", "src/main/kotlin", "src/test/scala", "src/main/scala", "src/test/groovy", "src/integrationTest/java", "src/it/java", "src", "test", "tests", "lib"}
+
+// sourceExtensions are the extensions of the files a classname could name
+var sourceExtensions = []string{".java", ".kt", ".scala", ".groovy", ".py"}
+
+// Locate finds where the failure of the testcase happened within the
+// repository. That is where its stack trace points, as with Locate, with
+// partial paths such as those of jvm frames and go test output resolved
+// against the repository using its classname. When the failure has no stack
+// trace, the file its classname names is located without a line.
+func (l *Locator) Locate(testcase Case) (Location, bool) {
+	location, ok := Locate(testcase.Failure.Message, l.Root)
+	if l.FS != nil && !strings.ContainsAny(testcase.Classname, " \t") {
+		if file, found := l.resolve(testcase.Classname, location.File); found {
+			location.File = file
+			ok = true
+		}
+	}
+	if !ok {
+		return Location{}, false
+	}
+
+	if l.SourceUrl != "" {
+		location.Url = strings.TrimSuffix(l.SourceUrl, "/") + "/" + (&url.URL{Path: location.File}).EscapedPath()
+		if location.Line > 0 {
+			location.Url += fmt.Sprintf("#L%d", location.Line)
+		}
+	}
+	return location, true
+}
+
+// resolve finds the file in the repository for the classname and the file,
+// if any, that its stack trace names, reporting whether the repository has it
+func (l *Locator) resolve(classname string, file string) (string, bool) {
+	if file != "" && l.has(file) {
+		return file, true
+	}
+	for _, candidate := range l.candidates(classname, file) {
+		if l.has(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// candidates are the files the classname and file could be, from the
+// mappings followed by the built-in source roots
+func (l *Locator) candidates(classname string, file string) []string {
+	if i := strings.Index(classname, "$"); i > 0 {
+		classname = classname[:i]
+	}
+
+	var candidates []string
+	for _, mapping := range l.mappings() {
+		rest, ok := trimPackage(classname, mapping.Prefix)
+		if !ok {
+			continue
+		}
+		if file != "" {
+			// a go classname is the package the file is in, while a jvm
+			// classname is the class the file is named for
+			candidates = append(candidates, path.Join(mapping.Path, rest, path.Base(file)), path.Join(mapping.Path, path.Dir(rest), path.Base(file)))
+		}
+		candidates = append(candidates, moduleFiles(path.Join(mapping.Path, rest))...)
+	}
+
+	if classname == "" {
+		return candidates
+	}
+	dotted := strings.ReplaceAll(classname, ".", "/")
+	for _, root := range l.sourceRoots() {
+		if file != "" && !strings.HasPrefix(file, "/") {
+			candidates = append(candidates, path.Join(root, file))
+		}
+		candidates = append(candidates, moduleFiles(path.Join(root, dotted))...)
+	}
+	return candidates
+}
+
+// mappings are the configured mappings followed by one for the go module at
+// the root of the repository, whose packages are named by their import path
+// in go test reports
+func (l *Locator) mappings() []PathMapping {
+	if l.mapping != nil {
+		return l.mapping
+	}
+	l.mapping = append([]PathMapping{}, l.Paths...)
+	if module := goModule(l.FS); module != "" {
+		l.mapping = append(l.mapping, PathMapping{Prefix: module, Path: "."})
+	}
+	return l.mapping
+}
+
+// sourceRoots are the directories of the repository that could hold the
+// packages and modules classnames name, starting with the repository itself
+func (l *Locator) sourceRoots() []string {
+	if l.roots != nil {
+		return l.roots
+	}
+	l.roots = []string{"."}
+	for _, root := range sourceRoots {
+		if l.has(root) {
+			l.roots = append(l.roots, root)
+		}
+	}
+	for _, root := range sourceRoots {
+		// src alone would match most directories of most repositories
+		if !strings.Contains(root, "/") {
+			continue
+		}
+		if matches, err := fs.Glob(l.FS, "*/"+root); err == nil {
+			l.roots = append(l.roots, matches...)
+		}
+	}
+	return l.roots
+}
+
+// has reports whether the repository has the file or directory, remembering
+// the answer as many testcases share classnames
+func (l *Locator) has(name string) bool {
+	name = path.Clean(name)
+	if !fs.ValidPath(name) || name == "." {
+		return false
+	}
+	if exists, ok := l.exists[name]; ok {
+		return exists
+	}
+	if l.exists == nil {
+		l.exists = map[string]bool{}
+	}
+	_, err := fs.Stat(l.FS, name)
+	l.exists[name] = err == nil
+	return err == nil
+}
+
+// trimPackage returns the rest of the classname after the prefix as a path,
+// reporting whether the classname is within the prefix
+func trimPackage(classname string, prefix string) (string, bool) {
+	prefix = strings.TrimRight(prefix, "./")
+	if prefix == "" || !strings.HasPrefix(classname, prefix) {
+		return "", false
+	}
+	rest := classname[len(prefix):]
+	if rest == "" {
+		return ".", true
+	}
+	if rest[0] != '.' && rest[0] != '/' {
+		return "", false
+	}
+	return strings.ReplaceAll(strings.TrimLeft(rest, "./"), ".", "/"), true
+}
+
+// moduleFiles are the source files the path of a classname could be, which
+// are named for either its last part, as with a jvm class or python module,
+// or the part before it, as with the class of a python module
+func moduleFiles(name string) []string {
+	var files []string
+	for _, candidate := range []string{name, path.Dir(name)} {
+		if candidate == "." || candidate == "/" {
+			continue
+		}
+		for _, extension := range sourceExtensions {
+			files = append(files, candidate+extension)
+		}
+	}
+	return files
+}
+
+// goModule is the path of the go module at the root of the repository, if
+// there is one
+func goModule(fsys fs.FS) string {
+	file, err := fsys.Open("go.mod")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// LocateFailures sets the location of each failing testcase of the report
+// that does not have one to where the locator finds its failure happened
+func LocateFailures(report Report, locator *Locator) Report {
+	located := report
+	located.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if testcase.Failed() && testcase.Location == nil {
+				if location, ok := locator.Locate(testcase); ok {
+					testcase.Location = &location
+				}
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		located.Suites[i] = suite
+	}
+	return located
+}
@@ -0,0 +1,61 @@
+package junit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Quarantine matches testcases known to be unreliable, whose failures are
+// reported without counting against the run
+type Quarantine struct {
+	patterns []*regexp.Regexp
+}
+
+// ReadQuarantine reads a quarantine list with a testcase id on each line, in
+// the form classname.name, where * matches any characters. Blank lines and
+// lines starting with # are ignored.
+func ReadQuarantine(r io.Reader) (Quarantine, error) {
+	var quarantine Quarantine
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+
+		compiled, err := compileGlob(id)
+		if err != nil {
+			return quarantine, fmt.Errorf("line %d: %s", line, err)
+		}
+		quarantine.patterns = append(quarantine.patterns, compiled)
+	}
+	return quarantine, scanner.Err()
+}
+
+// compileGlob compiles a pattern where * matches any characters
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	return regexp.Compile("^" + strings.Replace(regexp.QuoteMeta(glob), `\*`, ".*", -1) + "$")
+}
+
+// Match reports whether the testcase is quarantined
+func (q Quarantine) Match(testcase Case) bool {
+	for _, pattern := range q.patterns {
+		if pattern.MatchString(testcase.Id()) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkQuarantined marks the failures of quarantined testcases so they no
+// longer count against the run, listing them in the report
+func MarkQuarantined(report Report, quarantine Quarantine) Report {
+	marked, quarantined := reclassify(report, StatusQuarantined, quarantine.Match)
+	marked.QuarantinedTests = append(marked.QuarantinedTests, quarantined...)
+	return marked
+}
@@ -0,0 +1,365 @@
+package junit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status is the outcome of a testcase
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusError   Status = "error"
+	StatusSkipped Status = "skipped"
+	// StatusFlaky is a failure of a testcase that passed elsewhere in the run
+	StatusFlaky Status = "flaky"
+	// StatusQuarantined is a failure of a testcase known to be unreliable
+	StatusQuarantined Status = "quarantined"
+)
+
+// Case is a single testcase, with its time in seconds
+type Case struct {
+	Classname  string  `json:"classname"`
+	Name       string  `json:"name"`
+	Time       float64 `json:"time"`
+	Assertions int     `json:"assertions,omitempty"`
+	Status     Status  `json:"status"`
+	Failure    Failure `json:"failure"`
+	// Location is where in the repository the testcase failed, when its
+	// failure has a stack trace pointing there
+	Location *Location `json:"location,omitempty"`
+	// SkipMessage is why the testcase was skipped, when it says
+	SkipMessage string   `json:"skip_message,omitempty"`
+	History     *History `json:"history,omitempty"`
+	// FailingSince is the commit the testcase has failed on since, when the
+	// previous run on the pull request also failed it, and NewFailure is
+	// whether the previous run did not
+	FailingSince string `json:"failing_since,omitempty"`
+	NewFailure   bool   `json:"new_failure,omitempty"`
+	// Reproduce is a command that runs the testcase alone, when it failed and
+	// its framework is known
+	Reproduce string `json:"reproduce,omitempty"`
+	// Attachments are the files the testcase attached, such as screenshots
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Owners are the people or teams responsible for the testcase, from its
+	// properties or an owners file
+	Owners []string `json:"owners,omitempty"`
+	// Slow is whether the testcase took longer than the slow threshold
+	Slow bool `json:"slow,omitempty"`
+	// DisplayName and DisplayClassname are what the testcase is rendered as,
+	// when its names were prettified
+	DisplayName      string `json:"display_name,omitempty"`
+	DisplayClassname string `json:"display_classname,omitempty"`
+}
+
+// History is how often a testcase failed over its recent runs, including the
+// current one, when the runs are recorded
+type History struct {
+	Failures int `json:"failures"`
+	Runs     int `json:"runs"`
+}
+
+// Failure is the message reported by a failing testcase
+type Failure struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Id identifies the testcase as classname.name
+func (c Case) Id() string {
+	if c.Classname == "" {
+		return c.Name
+	}
+	return c.Classname + "." + c.Name
+}
+
+// Display is the name the testcase is rendered with
+func (c Case) Display() string {
+	if c.DisplayName != "" {
+		return c.DisplayName
+	}
+	return c.Name
+}
+
+// DisplayId is the id the testcase is rendered with, which is its id unless
+// its names were prettified
+func (c Case) DisplayId() string {
+	classname := c.Classname
+	if c.DisplayClassname != "" {
+		classname = c.DisplayClassname
+	}
+	if classname == "" {
+		return c.Display()
+	}
+	return classname + "." + c.Display()
+}
+
+// Failed reports whether the testcase failed or errored
+func (c Case) Failed() bool {
+	return c.Status == StatusFailed || c.Status == StatusError
+}
+
+func (c Case) Duration() time.Duration {
+	return seconds(c.Time)
+}
+
+// Summary counts the testcases of one or more suites
+type Summary struct {
+	Tests       int `json:"tests"`
+	Failures    int `json:"failures"`
+	Errors      int `json:"errors"`
+	Skipped     int `json:"skipped"`
+	Flaky       int `json:"flaky,omitempty"`
+	Quarantined int `json:"quarantined,omitempty"`
+	// Assertions is how many assertions were checked, for the frameworks
+	// that report it
+	Assertions int `json:"assertions,omitempty"`
+}
+
+func (s *Summary) Add(suite Suite) {
+	s.Tests += suite.Tests
+	s.Failures += suite.Failures
+	s.Errors += suite.Errors
+	s.Skipped += suite.Skipped
+	s.Flaky += suite.Flaky
+	s.Quarantined += suite.Quarantined
+	s.Assertions += suite.Assertions
+}
+
+func (s Summary) Failed() bool {
+	return s.Failures > 0 || s.Errors > 0
+}
+
+func (s Summary) Passed() int {
+	return s.Tests - s.Failures - s.Errors - s.Skipped - s.Flaky - s.Quarantined
+}
+
+// PassRate is the percentage of executed testcases that passed, which is 100
+// when nothing was executed. Flaky and quarantined failures are left out, as
+// they do not count against the run.
+func (s Summary) PassRate() float64 {
+	executed := s.Tests - s.Skipped - s.Flaky - s.Quarantined
+	if executed <= 0 {
+		return 100
+	}
+	return float64(s.Passed()) / float64(executed) * 100
+}
+
+func (s Summary) String() string {
+	summary := fmt.Sprintf("%d tests, %d failures, %d errors, %d skipped", s.Tests, s.Failures, s.Errors, s.Skipped)
+	if s.Flaky > 0 {
+		summary += fmt.Sprintf(", %d flaky", s.Flaky)
+	}
+	if s.Quarantined > 0 {
+		summary += fmt.Sprintf(", %d quarantined", s.Quarantined)
+	}
+	return summary
+}
+
+// Suite is a named group of testcases, with its counts as reported, its time
+// in seconds, and any labels for the matrix build it was run in
+type Suite struct {
+	Name string `json:"name"`
+	Summary
+	Time      float64           `json:"time"`
+	Timestamp string            `json:"timestamp"`
+	Hostname  string            `json:"hostname"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Cases     []Case            `json:"testcases"`
+}
+
+func (s Suite) Duration() time.Duration {
+	return seconds(s.Time)
+}
+
+// timestampLayouts are the layouts accepted for suite timestamps, which are
+// taken to be in utc when they have no timezone
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Started parses the timestamp of the suite, returning the zero time when it
+// is missing or in an unknown layout
+func (s Suite) Started() time.Time {
+	timestamp := strings.TrimSpace(s.Timestamp)
+	if timestamp == "" {
+		return time.Time{}
+	}
+	for _, layout := range timestampLayouts {
+		if started, err := time.Parse(layout, timestamp); err == nil {
+			return started
+		}
+	}
+	return time.Time{}
+}
+
+// Slowest returns up to n testcases, slowest first
+func (s Suite) Slowest(n int) []Case {
+	return slowest(s.Cases, n)
+}
+
+// Names returns a name for each testcase that tells it apart from the others
+// in the suite. Names shared by several testcases, such as those of
+// parameterized or repeated tests, are qualified with their classnames, and
+// testcases that still share a name are numbered by occurrence, such as
+// "test_login (2 of 3)".
+func (s Suite) Names() []string {
+	names := map[string]int{}
+	for _, testcase := range s.Cases {
+		names[testcase.Display()]++
+	}
+
+	qualified := make([]string, len(s.Cases))
+	occurrences := map[string]int{}
+	for i, testcase := range s.Cases {
+		qualified[i] = testcase.Display()
+		if names[testcase.Display()] > 1 {
+			qualified[i] = testcase.DisplayId()
+		}
+		occurrences[qualified[i]]++
+	}
+
+	seen := map[string]int{}
+	for i, name := range qualified {
+		if occurrences[name] > 1 {
+			seen[name]++
+			qualified[i] = fmt.Sprintf("%s (%d of %d)", name, seen[name], occurrences[name])
+		}
+	}
+	return qualified
+}
+
+// Count recomputes the counts of the suite from its testcases. The assertions
+// of the suite are kept as reported unless its testcases report their own.
+func (s *Suite) Count() {
+	assertions := s.Assertions
+	if total := caseAssertions(s.Cases); total > 0 {
+		assertions = total
+	}
+	s.Summary = Summary{Tests: len(s.Cases), Assertions: assertions}
+	for _, testcase := range s.Cases {
+		switch testcase.Status {
+		case StatusFailed:
+			s.Failures++
+		case StatusError:
+			s.Errors++
+		case StatusSkipped:
+			s.Skipped++
+		case StatusFlaky:
+			s.Flaky++
+		case StatusQuarantined:
+			s.Quarantined++
+		}
+	}
+}
+
+// Report is the suites read from one or more xml reports, along with their
+// combined counts, any flaky, quarantined, slow, or slower than baseline
+// testcases, the coverage and benchmarks of the run when they are known,
+// the reports that could not be parsed or found, the reports left unparsed
+// when parsing was stopped early, the required suites that are missing, and
+// the rendered body once published
+type Report struct {
+	Summary          `json:"summary"`
+	Suites           []Suite      `json:"testsuites"`
+	FlakyTests       []Case       `json:"flaky_tests,omitempty"`
+	QuarantinedTests []Case       `json:"quarantined_tests,omitempty"`
+	SlowTests        []Case       `json:"slow_tests,omitempty"`
+	ParseErrors      []ParseError `json:"parse_errors,omitempty"`
+	MissingPaths     []string     `json:"missing_paths,omitempty"`
+	Unparsed         []string     `json:"unparsed,omitempty"`
+	MissingSuites    []string     `json:"missing_suites,omitempty"`
+	SlowerTests      []Slowdown   `json:"slower_tests,omitempty"`
+	Coverage         *Coverage    `json:"coverage,omitempty"`
+	Benchmarks       []Benchmark  `json:"benchmarks,omitempty"`
+	Body             string       `json:"body,omitempty"`
+}
+
+// ParseError is a report that could not be parsed
+type ParseError struct {
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+func (e ParseError) Error() string {
+	return e.File + ": " + e.Message
+}
+
+// Add appends the suite, adding its counts to the summary
+func (r *Report) Add(suite Suite) {
+	r.Summary.Add(suite)
+	r.Suites = append(r.Suites, suite)
+}
+
+// Recount recomputes the counts of every suite from its testcases, along with
+// the summary, such as after results have been edited outside of this package
+func (r *Report) Recount() {
+	r.Summary = Summary{}
+	for i := range r.Suites {
+		r.Suites[i].Count()
+		r.Summary.Add(r.Suites[i])
+	}
+}
+
+// Duration is the combined time of the suites
+func (r Report) Duration() time.Duration {
+	var duration time.Duration
+	for _, suite := range r.Suites {
+		duration += suite.Duration()
+	}
+	return duration
+}
+
+// Started is when the earliest suite with a timestamp started, or the zero
+// time when no suite has one
+func (r Report) Started() time.Time {
+	var earliest time.Time
+	for _, suite := range r.Suites {
+		started := suite.Started()
+		if !started.IsZero() && (earliest.IsZero() || started.Before(earliest)) {
+			earliest = started
+		}
+	}
+	return earliest
+}
+
+// Slowest returns up to n testcases across all suites, slowest first
+func (r Report) Slowest(n int) []Case {
+	var cases []Case
+	for _, suite := range r.Suites {
+		cases = append(cases, suite.Cases...)
+	}
+	return slowest(cases, n)
+}
+
+// caseAssertions totals the assertions of the testcases
+func caseAssertions(cases []Case) int {
+	total := 0
+	for _, testcase := range cases {
+		total += testcase.Assertions
+	}
+	return total
+}
+
+func slowest(cases []Case, n int) []Case {
+	sorted := make([]Case, len(cases))
+	copy(sorted, cases)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time > sorted[j].Time
+	})
+	if n >= 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
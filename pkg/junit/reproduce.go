@@ -0,0 +1,226 @@
+package junit
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// ReproduceTarget is what a reproduce template is executed with, naming the
+// testcase in the terms of its framework
+type ReproduceTarget struct {
+	// Framework is go, python, or java
+	Framework string
+	Classname string
+	Name      string
+	// Package is the import path of a go package, the file of a python
+	// module, or the package of a java class
+	Package string
+	// Class is the python classes, separated by ::, or the fully qualified
+	// java class the testcase is in, if any
+	Class string
+	// Method is the name of the test function or method, without the
+	// parameters of java tests
+	Method string
+	// Selector is what the test runner selects the testcase with, such as
+	// ^TestLogin$ for go test -run, tests/test_login.py::test_login for
+	// pytest, or com.example.LoginTest#testLogin for maven surefire
+	Selector string
+}
+
+// DefaultReproduceTemplates are the commands of each framework that run a
+// failing testcase alone
+var DefaultReproduceTemplates = map[string]string{
+	"go":     "go test {{ quote .Package }} -run {{ quote .Selector }}",
+	"python": "pytest {{ quote .Selector }}",
+	"java":   "mvn test -Dtest={{ quote .Selector }}",
+}
+
+// ReproduceFrameworks are the frameworks reproduce templates can be given for
+func ReproduceFrameworks() []string {
+	frameworks := make([]string, 0, len(DefaultReproduceTemplates))
+	for framework := range DefaultReproduceTemplates {
+		frameworks = append(frameworks, framework)
+	}
+	sort.Strings(frameworks)
+	return frameworks
+}
+
+// Reproducer renders the commands that run failing testcases alone
+type Reproducer struct {
+	templates map[string]*template.Template
+}
+
+var reproduceFuncs = template.FuncMap{"quote": shellQuote}
+
+// NewReproducer parses the templates of each framework, which replace the
+// defaults, checking that they can be executed
+func NewReproducer(templates map[string]string) (Reproducer, error) {
+	reproducer := Reproducer{templates: map[string]*template.Template{}}
+	for framework := range templates {
+		if _, ok := DefaultReproduceTemplates[framework]; !ok {
+			return reproducer, fmt.Errorf("unknown reproduce framework %s, expected one of %s", framework, strings.Join(ReproduceFrameworks(), ", "))
+		}
+	}
+	for _, framework := range ReproduceFrameworks() {
+		text, ok := templates[framework]
+		if !ok {
+			text = DefaultReproduceTemplates[framework]
+		}
+		parsed, err := template.New(framework).Funcs(reproduceFuncs).Option("missingkey=error").Parse(text)
+		if err == nil {
+			err = parsed.Execute(&strings.Builder{}, ReproduceTarget{Framework: framework})
+		}
+		if err != nil {
+			return reproducer, fmt.Errorf("invalid %s reproduce template: %s", framework, err)
+		}
+		reproducer.templates[framework] = parsed
+	}
+	return reproducer, nil
+}
+
+// Command is the command that runs the testcase of the suite alone, or empty
+// when its framework cannot be told from its names and location
+func (r Reproducer) Command(suite Suite, testcase Case) string {
+	target, ok := reproduceTarget(suite, testcase)
+	if !ok || r.templates[target.Framework] == nil {
+		return ""
+	}
+	var command strings.Builder
+	if err := r.templates[target.Framework].Execute(&command, target); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(command.String())
+}
+
+// AddReproduceCommands sets the command that runs each failing testcase of
+// the report alone, for those whose framework is known
+func AddReproduceCommands(report Report, reproducer Reproducer) Report {
+	added := report
+	added.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if testcase.Failed() {
+				testcase.Reproduce = reproducer.Command(suite, testcase)
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		added.Suites[i] = suite
+	}
+	return added
+}
+
+// goTestPattern matches the names of go tests, examples, and fuzz tests,
+// with any subtests
+var goTestPattern = regexp.MustCompile(`^(Test|Example|Fuzz)[^/]*(/.+)?$`)
+
+// reproduceTarget names the testcase in the terms of its framework, which is
+// told from the file it failed in when it is located, and otherwise from the
+// shape of its names: go-junit-report names classnames after the import path
+// of the package, pytest names its suites pytest and its modules test_*, and
+// java classnames end with the name of a class
+func reproduceTarget(suite Suite, testcase Case) (ReproduceTarget, bool) {
+	target := ReproduceTarget{Classname: testcase.Classname, Name: testcase.Name}
+	if testcase.Location != nil {
+		switch path.Ext(testcase.Location.File) {
+		case ".go":
+			target.Framework = "go"
+		case ".py":
+			target.Framework = "python"
+		case ".java", ".kt", ".scala", ".groovy":
+			target.Framework = "java"
+		}
+	}
+	segments := strings.Split(testcase.Classname, ".")
+	if target.Framework == "" {
+		switch {
+		case goTestPattern.MatchString(testcase.Name) && strings.Contains(testcase.Classname, "/"):
+			target.Framework = "go"
+		case suite.Name == "pytest" || pythonModule(segments):
+			target.Framework = "python"
+		case testcase.Classname != "" && startsUpper(segments[len(segments)-1]):
+			target.Framework = "java"
+		default:
+			return target, false
+		}
+	}
+
+	switch target.Framework {
+	case "go":
+		target.Package, target.Method = testcase.Classname, testcase.Name
+		var parts []string
+		for _, part := range strings.Split(testcase.Name, "/") {
+			parts = append(parts, "^"+regexp.QuoteMeta(part)+"$")
+		}
+		target.Selector = strings.Join(parts, "/")
+	case "python":
+		modules := len(segments)
+		for i, segment := range segments {
+			if startsUpper(segment) {
+				modules = i
+				break
+			}
+		}
+		switch {
+		case testcase.Location != nil && path.Ext(testcase.Location.File) == ".py":
+			target.Package = testcase.Location.File
+		case modules > 0 && segments[0] != "":
+			target.Package = strings.Join(segments[:modules], "/") + ".py"
+		default:
+			return target, false
+		}
+		target.Class = strings.Join(segments[modules:], "::")
+		target.Method = testcase.Name
+		target.Selector = target.Package
+		if target.Class != "" {
+			target.Selector += "::" + target.Class
+		}
+		target.Selector += "::" + target.Method
+	case "java":
+		target.Class = testcase.Classname
+		if i := strings.LastIndex(testcase.Classname, "."); i >= 0 {
+			target.Package = testcase.Classname[:i]
+		}
+		target.Method = testcase.Name
+		if i := strings.IndexAny(target.Method, "(["); i > 0 {
+			target.Method = target.Method[:i]
+		}
+		target.Selector = target.Class + "#" + target.Method
+	}
+	return target, target.Package != "" || target.Class != ""
+}
+
+// pythonModule reports whether the classname has a pytest module, named
+// test_* or *_test
+func pythonModule(segments []string) bool {
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, "test_") || strings.HasSuffix(segment, "_test") {
+			return true
+		}
+	}
+	return false
+}
+
+func startsUpper(s string) bool {
+	for _, r := range s {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// shellQuote quotes the value for a posix shell when it has characters the
+// shell would interpret
+func shellQuote(value string) string {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_./:=@,+#%", r))
+	}) < 0 {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
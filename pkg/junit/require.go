@@ -0,0 +1,26 @@
+package junit
+
+// MissingSuites returns the names that no suite of the report has, where *
+// matches any characters, such as the suites of shards whose reports were
+// never uploaded
+func MissingSuites(report Report, names []string) ([]string, error) {
+	var missing []string
+	for _, name := range names {
+		pattern, err := compileGlob(name)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, suite := range report.Suites {
+			if pattern.MatchString(suite.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
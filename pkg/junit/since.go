@@ -0,0 +1,26 @@
+package junit
+
+// MarkFailingSince marks each failing testcase of the report as failing since
+// the commit an earlier run on the pull request first failed it on, from the
+// ids of the testcases that run left failing, or as newly failing when the
+// earlier run did not fail it
+func MarkFailingSince(report Report, failing map[string]string) Report {
+	marked := report
+	marked.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if testcase.Failed() {
+				if since, ok := failing[testcase.Id()]; ok {
+					testcase.FailingSince = since
+				} else {
+					testcase.NewFailure = true
+				}
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		marked.Suites[i] = suite
+	}
+	return marked
+}
@@ -0,0 +1,30 @@
+package junit
+
+import "time"
+
+// MarkSlow marks the testcases that took longer than the threshold as slow
+// and lists them in the report, slowest first, so that creeping slowness is
+// noticed as it happens. Skipped testcases are never slow.
+func MarkSlow(report Report, threshold time.Duration) Report {
+	if threshold <= 0 {
+		return report
+	}
+
+	marked := report
+	marked.Suites = make([]Suite, len(report.Suites))
+	var slow []Case
+	for i, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if testcase.Status != StatusSkipped && testcase.Duration() > threshold {
+				testcase.Slow = true
+				slow = append(slow, testcase)
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		marked.Suites[i] = suite
+	}
+	marked.SlowTests = slowest(slow, -1)
+	return marked
+}
@@ -0,0 +1,302 @@
+package junit
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Location is the file and line in the repository that a failure happened
+// at, with a url to view the line at when the source is known. The line is 0
+// when only the file is known.
+type Location struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Url  string `json:"url,omitempty"`
+}
+
+func (l Location) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Frame is a frame of a stack trace in a failure message
+type Frame struct {
+	Language string
+	File     string
+	Line     int
+	// Function is the function or method of the frame, for the languages
+	// whose traces name it
+	Function string
+}
+
+// framePatterns match a line of the stack traces printed by each language,
+// capturing the file, line, and function of the frame
+var framePatterns = []struct {
+	language                string
+	pattern                 *regexp.Regexp
+	file, line, function    int
+	innermostLast, packaged bool
+}{
+	// at com.example.LoginTest.testLogin(LoginTest.java:42), optionally with
+	// the module of the class, such as app// or java.base/
+	{language: "java", pattern: regexp.MustCompile(`^\s*at\s+(?:[\w.$-]+/+)?((?:[\w$]+\.)+[\w$<>]+)\(([\w$]+\.(?:java|kt|scala|groovy)):(\d+)\)`), function: 1, file: 2, line: 3, packaged: true},
+	// File "tests/test_login.py", line 42, in test_login
+	{language: "python", pattern: regexp.MustCompile(`^\s*File "([^"]+\.py)", line (\d+)(?:, in (\S+))?`), file: 1, line: 2, function: 3, innermostLast: true},
+	// tests/test_login.py:42: AssertionError, as summarized by pytest
+	{language: "python", pattern: regexp.MustCompile(`^((?:[A-Za-z]:)?[^\s:"]+\.py):(\d+):`), file: 1, line: 2, innermostLast: true},
+	// at login (/repo/src/login.test.js:42:13) or at /repo/src/login.js:42:13,
+	// or at node:internal/process/task_queues:95:5 for node itself
+	{language: "javascript", pattern: regexp.MustCompile(`^\s*at\s+(?:(.+?)\s+\()?(?:file://)?((?:[A-Za-z]:)?[^\s()]+\.(?:js|jsx|ts|tsx|mjs|cjs|vue)|node:[\w/.-]+):(\d+)(?::\d+)?\)?\s*$`), function: 1, file: 2, line: 3},
+	// spec/login_spec.rb:42:in `block (2 levels) in <top (required)>', as
+	// printed by minitest, or prefixed with # by rspec
+	{language: "ruby", pattern: regexp.MustCompile("^\\s*(?:#\\s+)?((?:[A-Za-z]:)?[^\\s:]+\\.rb):(\\d+)(?::in\\s+[`'](.+)')?"), file: 1, line: 2, function: 3},
+	// /repo/login_test.go:42 +0x1d in a panic, or login_test.go:42: in the
+	// output of a failing test
+	{language: "go", pattern: regexp.MustCompile(`^\s*((?:[A-Za-z]:)?[^\s:]+\.go):(\d+)`), file: 1, line: 2},
+}
+
+// libraryPaths are the directories that dependencies and runtimes are
+// installed in, whose frames are not in the repository
+var libraryPaths = []string{"node_modules/", "site-packages/", "dist-packages/", "lib/python2.", "lib/python3.", "vendor/", ".bundle/", "gems/", "go/src/runtime/", "go/src/testing/", "go/src/reflect/", "<frozen "}
+
+// libraryPackages are the packages of the jvm runtime, languages, and test
+// frameworks, whose frames are not in the repository
+var libraryPackages = []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "kotlinx.", "scala.", "groovy.", "org.codehaus.groovy.", "org.junit.", "junit.", "org.testng.", "org.gradle.", "org.apache.maven.", "org.spockframework."}
+
+// ParseFrames returns the frames of the stack traces in the message, in the
+// order they appear
+func ParseFrames(message string) []Frame {
+	var frames []Frame
+	for _, line := range strings.Split(message, "\n") {
+		if frame, ok := ParseFrame(line); ok {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// ParseFrame parses a line of a stack trace, reporting whether it is one
+func ParseFrame(line string) (Frame, bool) {
+	for _, frame := range framePatterns {
+		match := frame.pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		number, err := strconv.Atoi(match[frame.line])
+		if err != nil || number == 0 {
+			continue
+		}
+		parsed := Frame{Language: frame.language, File: match[frame.file], Line: number}
+		if frame.function > 0 {
+			parsed.Function = match[frame.function]
+		}
+		if frame.packaged {
+			parsed.File = packagePath(parsed.Function, parsed.File)
+		}
+		return parsed, true
+	}
+	return Frame{}, false
+}
+
+// packagePath is the path of a jvm source file within its source root, such
+// as com/example/LoginTest.java, from the method and file name of its frame
+func packagePath(function string, file string) string {
+	parts := strings.Split(function, ".")
+	if len(parts) <= 2 {
+		return file
+	}
+	return strings.Join(parts[:len(parts)-2], "/") + "/" + file
+}
+
+// Library reports whether the frame is in a dependency, runtime, or test
+// framework rather than the code under test
+func (f Frame) Library() bool {
+	if f.Language == "java" {
+		for _, prefix := range libraryPackages {
+			if strings.HasPrefix(f.Function, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	if f.Language == "javascript" && strings.HasPrefix(f.File, "node:") {
+		return true
+	}
+
+	file := "/" + strings.ReplaceAll(f.File, "\\", "/")
+	for _, directory := range libraryPaths {
+		if strings.Contains(file, "/"+directory) {
+			return true
+		}
+	}
+	return false
+}
+
+// Locate finds the most relevant frame of the stack traces in the message
+// that is in the repository at root, returning its path relative to root.
+// That is the innermost frame outside of dependencies and runtimes, which is
+// the last one for python and the first for the other languages.
+func Locate(message string, root string) (Location, bool) {
+	frames := ParseFrames(message)
+	if len(frames) > 0 && innermostLast(frames[0].Language) {
+		for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+			frames[i], frames[j] = frames[j], frames[i]
+		}
+	}
+
+	for _, frame := range frames {
+		if frame.Library() {
+			continue
+		}
+		if file, ok := repositoryPath(frame.File, root); ok {
+			return Location{File: file, Line: frame.Line}, true
+		}
+	}
+	return Location{}, false
+}
+
+func innermostLast(language string) bool {
+	for _, frame := range framePatterns {
+		if frame.language == language {
+			return frame.innermostLast
+		}
+	}
+	return false
+}
+
+// repositoryPath is the file relative to root, reporting whether it is
+// within root. Relative files are taken to already be relative to root.
+func repositoryPath(file string, root string) (string, bool) {
+	file = strings.ReplaceAll(strings.TrimPrefix(file, "file://"), "\\", "/")
+	if !isAbsolute(file) {
+		file = path.Clean(file)
+		return file, file != "." && !strings.HasPrefix(file, "../")
+	}
+
+	root = path.Clean(strings.ReplaceAll(root, "\\", "/"))
+	if root == "." || !isAbsolute(root) {
+		return "", false
+	}
+	file = path.Clean(file)
+	if !strings.HasPrefix(file, strings.TrimSuffix(root, "/")+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(file[len(root):], "/"), true
+}
+
+// isAbsolute reports whether a slash separated path is absolute on unix or
+// windows
+func isAbsolute(file string) bool {
+	return strings.HasPrefix(file, "/") || (len(file) > 2 && file[1] == ':' && file[2] == '/')
+}
+
+// foldMinimum is the fewest consecutive framework frames worth folding
+const foldMinimum = 2
+
+// anonymousFrame matches the frames of javascript runtimes without a file,
+// such as at new Promise (<anonymous>)
+var anonymousFrame = regexp.MustCompile(`^\s*at\s+.+\((?:<anonymous>|native)\)\s*$`)
+
+// FoldFrames replaces each run of consecutive frames in the stack traces of
+// the message that are outside of the repository at root, such as those of
+// test frameworks, runtimes, and dependencies, with a line counting them, so
+// that the frames of the code under test stand out. When no frame is in the
+// repository, such as when the tests ran in a container that checked it out
+// elsewhere, only the frames of dependencies and runtimes are folded.
+func FoldFrames(message string, root string) string {
+	_, located := Locate(message, root)
+	lines := strings.Split(message, "\n")
+	var folded []string
+	var run []string
+	frames := 0
+	flush := func() {
+		if frames < foldMinimum {
+			folded = append(folded, run...)
+		} else {
+			indent := run[0][:len(run[0])-len(strings.TrimLeft(run[0], " \t"))]
+			folded = append(folded, fmt.Sprintf("%s… %d framework frames", indent, frames))
+		}
+		run = nil
+		frames = 0
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if anonymousFrame.MatchString(line) {
+			run = append(run, line)
+			frames++
+			continue
+		}
+		frame, ok := ParseFrame(line)
+		if !ok || !frame.framework(root, located) {
+			if len(run) > 0 {
+				flush()
+			}
+			folded = append(folded, line)
+			continue
+		}
+
+		run = append(run, line)
+		frames++
+		// python tracebacks follow each frame with the line of source it ran
+		if frame.Language == "python" && strings.Contains(line, `File "`) && i+1 < len(lines) && sourceLine(line, lines[i+1]) {
+			i++
+			run = append(run, lines[i])
+		}
+	}
+	if len(run) > 0 {
+		flush()
+	}
+	return strings.Join(folded, "\n")
+}
+
+// framework reports whether the frame is outside of the code under test in
+// the repository at root, which is only known for dependencies and runtimes
+// unless the trace is located within the repository
+func (f Frame) framework(root string, located bool) bool {
+	if f.Library() {
+		return true
+	}
+	if f.Language == "java" || !located {
+		return false
+	}
+	_, ok := repositoryPath(f.File, root)
+	return !ok
+}
+
+// sourceLine reports whether next is the line of source printed beneath a
+// frame of a python traceback, which is indented further than the frame
+func sourceLine(frame string, next string) bool {
+	if strings.TrimSpace(next) == "" {
+		return false
+	}
+	if _, ok := ParseFrame(next); ok {
+		return false
+	}
+	return len(next)-len(strings.TrimLeft(next, " \t")) > len(frame)-len(strings.TrimLeft(frame, " \t"))
+}
+
+// FoldReport folds the framework frames of the failure messages of the
+// report, as with FoldFrames
+func FoldReport(report Report, root string) Report {
+	folded := report
+	folded.Suites = make([]Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		cases := make([]Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if testcase.Failed() {
+				testcase.Failure.Message = FoldFrames(testcase.Failure.Message, root)
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		folded.Suites[i] = suite
+	}
+	return folded
+}
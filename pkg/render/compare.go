@@ -0,0 +1,53 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// Comparison renders the difference between two runs as markdown
+func Comparison(comparison junit.Comparison) string {
+	body := "| | Tests | Failures | Errors | Skipped | Duration |\n|---|---|---|---|---|---|\n"
+	for _, run := range []struct {
+		name     string
+		summary  junit.Summary
+		duration float64
+	}{{"Old", comparison.Old, comparison.OldDuration}, {"New", comparison.New, comparison.NewDuration}} {
+		duration := time.Duration(run.duration * float64(time.Second)).Round(time.Millisecond)
+		body += fmt.Sprintf("| %s | %d | %d | %d | %d | %s |\n", run.name, run.summary.Tests, run.summary.Failures, run.summary.Errors, run.summary.Skipped, duration)
+	}
+	body += "\n"
+
+	if !comparison.Changed() {
+		return body + "No tests changed.\n"
+	}
+
+	if len(comparison.NewFailures) > 0 {
+		body += fmt.Sprintf("### New failures (%d)\n\n", len(comparison.NewFailures))
+		for _, testcase := range comparison.NewFailures {
+			body += "<details><summary>" + escape(testcase.DisplayId()) + "</summary>\n"
+			body += indented(testcase.Failure.Message, ioutil.Discard)
+			body += "</details>\n"
+		}
+		body += "\n"
+	}
+
+	for _, section := range []struct {
+		title string
+		cases []junit.Case
+	}{{"Fixed", comparison.Fixed}, {"Added", comparison.Added}, {"Removed", comparison.Removed}} {
+		if len(section.cases) == 0 {
+			continue
+		}
+		body += fmt.Sprintf("### %s (%d)\n\n", section.title, len(section.cases))
+		for _, testcase := range section.cases {
+			body += fmt.Sprintf("- %s\n", escape(testcase.DisplayId()))
+		}
+		body += "\n"
+	}
+
+	return body + Slower(comparison.Slower, ioutil.Discard)
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"trim":  strings.TrimSpace,
+	"short": shortCommit,
+	"number": func(i int) int {
+		return i + 1
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+.ok { color: #2e7d32; }
+.failed { color: #c62828; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<table>
+<tr><th>Tests</th><th>Failures</th><th>Errors</th><th>Skipped</th><th>Pass rate</th></tr>
+<tr><td>{{ .Summary.Tests }}</td><td>{{ .Summary.Failures }}</td><td>{{ .Summary.Errors }}</td><td>{{ .Summary.Skipped }}</td><td>{{ printf "%.1f" .Summary.PassRate }}%</td></tr>
+</table>
+{{- range .Suites }}
+<h2>1..{{ .Tests }} ({{ .Name }}){{ with .Label }} [{{ . }}]{{ end }}</h2>
+<ul>
+{{- $names := .Names }}
+{{- range $i, $testcase := .Cases }}
+{{- if $testcase.Failed }}
+<li class="failed">not ok {{ number $i }} {{ index $names $i }} in {{ $testcase.Time }}sec{{ if $testcase.Slow }} 🐢{{ end }}{{ if eq $testcase.Status "error" }} # error{{ end }}{{ with $testcase.History }} (failed {{ .Failures }} of last {{ .Runs }} runs){{ end }}{{ if $testcase.NewFailure }} (new in this run){{ else if $testcase.FailingSince }} (still failing since {{ short $testcase.FailingSince }}){{ end }}{{ with $testcase.Location }}<br>at {{ if .Url }}<a href="{{ .Url }}">{{ .String }}</a>{{ else }}<code>{{ .String }}</code>{{ end }}{{ end }}{{ with trim $testcase.Failure.Message }}<pre>{{ . }}</pre>{{ end }}{{ range $testcase.Attachments }}{{ if not .Url }}<p>📎 <code>{{ .Path }}</code></p>{{ else if .Image }}<p><img src="{{ .Url }}" alt="{{ .Name }}"></p>{{ else }}<p>📎 <a href="{{ .Url }}">{{ .Name }}</a></p>{{ end }}{{ end }}</li>
+{{- else }}
+<li class="ok">ok {{ number $i }} {{ index $names $i }} in {{ $testcase.Time }}sec{{ if $testcase.Slow }} 🐢{{ end }}{{ if ne $testcase.Status "passed" }} # {{ $testcase.Status }}{{ end }}{{ with $testcase.SkipMessage }}<pre>{{ . }}</pre>{{ end }}</li>
+{{- end }}
+{{- end }}
+</ul>
+{{- end }}
+</body>
+</html>
+`))
+
+// HTML renders the results as a standalone html page
+func HTML(title string, summary junit.Summary, suites []junit.Suite) (string, error) {
+	if title == "" {
+		title = "Test results"
+	}
+
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, map[string]interface{}{
+		"Title":   title,
+		"Summary": summary,
+		"Suites":  suites,
+	})
+	return buf.String(), err
+}
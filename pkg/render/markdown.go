@@ -0,0 +1,724 @@
+// Package render formats test results as markdown, tap, json, html, or with
+// a template
+package render
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// Suite renders the suite as markdown, echoing each line to the console as
+// tap-like output
+func Suite(testsuite junit.Suite, skipOk bool, console io.Writer) string {
+	body := suiteHeading(testsuite, skipOk, console)
+	names := testsuite.Names()
+	for i, testcase := range testsuite.Cases {
+		body += testcaseLine(i, names[i], testcase, skipOk, console)
+	}
+	return body
+}
+
+// suiteHeading renders the heading of the suite with its counts, unless ok
+// suites are skipped and it passed
+func suiteHeading(testsuite junit.Suite, skipOk bool, console io.Writer) string {
+	body := ""
+	if !skipOk || testsuite.Failed() {
+		message := fmt.Sprintf("1..%d (%s)", testsuite.Tests, testsuite.Name)
+		if label := testsuite.Label(); label != "" {
+			message += " [" + label + "]"
+		}
+		if counts := suiteCounts(testsuite.Summary); counts != "" {
+			message += " # " + counts
+		}
+		body += "### " + escape(message) + "\n\n"
+		fmt.Fprintln(console, message)
+	}
+	return body
+}
+
+// testcaseLine renders the i-th testcase of a suite under the name, numbered
+// from one as tap does, with its failure or skip message, or nothing when ok
+// testcases are skipped and it did not fail
+func testcaseLine(i int, name string, testcase junit.Case, skipOk bool, console io.Writer) string {
+	body := ""
+	if !testcase.Failed() {
+		if !skipOk {
+			message := fmt.Sprintf("ok %d %s in %ssec", i+1, name, seconds(testcase.Time))
+			if testcase.Slow {
+				message += " " + slowMarker
+			}
+			if testcase.Status != junit.StatusPassed {
+				message += " # " + string(testcase.Status)
+			}
+			fmt.Fprintln(console, message)
+			if testcase.SkipMessage == "" {
+				return "<details><summary>" + escape(message) + "</summary></details>\n"
+			}
+			body += "<details><summary>" + escape(message) + "</summary>\n"
+			body += indented(testcase.SkipMessage, console)
+			body += "</details>\n"
+		}
+	} else {
+		message := fmt.Sprintf("not ok %d %s in %ssec", i+1, name, seconds(testcase.Time))
+		if testcase.Slow {
+			message += " " + slowMarker
+		}
+		if testcase.Status == junit.StatusError {
+			message += " # error"
+		}
+		if testcase.History != nil {
+			message += fmt.Sprintf(" (failed %d of last %d runs)", testcase.History.Failures, testcase.History.Runs)
+		}
+		message += failingSince(testcase)
+		body += "<details><summary>" + escape(message) + "</summary>\n"
+		fmt.Fprintln(console, message)
+		body += failure(testcase, console)
+		body += "</details>\n"
+	}
+	return body
+}
+
+// failingSince notes whether the testcase newly failed in this run, or which
+// commit it has failed on since, when it is compared to a previous run
+func failingSince(testcase junit.Case) string {
+	if testcase.NewFailure {
+		return " (new in this run)"
+	}
+	if testcase.FailingSince != "" {
+		return " (still failing since " + shortCommit(testcase.FailingSince) + ")"
+	}
+	return ""
+}
+
+// shortCommit abbreviates a commit sha as git does
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// failure renders where the testcase failed, linking to the line when its
+// location has a url, followed by its failure message and attachments
+func failure(testcase junit.Case, console io.Writer) string {
+	if testcase.Location == nil {
+		return indented(testcase.Failure.Message, console) + attachments(testcase.Attachments, console)
+	}
+
+	location := "`" + testcase.Location.String() + "`"
+	if testcase.Location.Url != "" {
+		location = "[" + escape(testcase.Location.String()) + "](" + testcase.Location.Url + ")"
+	}
+	fmt.Fprintln(console, "    at "+testcase.Location.String())
+	return "\nat " + location + "\n" + indented(testcase.Failure.Message, console) + attachments(testcase.Attachments, console)
+}
+
+// attachments renders the uploaded attachments of a testcase, showing images
+// inline and linking to other files, and names those that were not uploaded
+func attachments(attachments []junit.Attachment, console io.Writer) string {
+	body := ""
+	for _, attachment := range attachments {
+		fmt.Fprintln(console, "    attachment "+attachment.Path)
+		switch {
+		case attachment.Url == "":
+			body += "\n📎 `" + attachment.Path + "`\n"
+		case attachment.Image():
+			body += "\n![" + escape(attachment.Name) + "](" + attachment.Url + ")\n"
+		default:
+			body += "\n📎 [" + escape(attachment.Name) + "](" + attachment.Url + ")\n"
+		}
+	}
+	return body
+}
+
+// indented renders a message as an indented code block between blank lines,
+// echoing each line to the console, or nothing when the message is empty
+func indented(message string, console io.Writer) string {
+	body := ""
+	if strings.TrimSpace(message) == "" {
+		return body
+	}
+	lines := strings.Split("\n"+strings.TrimSpace(message)+"\n", "\n")
+	for _, line := range lines {
+		line := fmt.Sprintf("    %v", line)
+		body += line + "\n"
+		fmt.Fprintln(console, line)
+	}
+	return body
+}
+
+// suiteCounts describes the testcases of a suite that did not pass, such as
+// "1 failure, 2 errors, 3 skipped"
+func suiteCounts(summary junit.Summary) string {
+	var counts []string
+	for _, count := range []struct {
+		count            int
+		singular, plural string
+	}{
+		{summary.Failures, "failure", "failures"},
+		{summary.Errors, "error", "errors"},
+		{summary.Skipped, "skipped", "skipped"},
+		{summary.Flaky, "flaky", "flaky"},
+		{summary.Quarantined, "quarantined", "quarantined"},
+	} {
+		switch {
+		case count.count == 1:
+			counts = append(counts, "1 "+count.singular)
+		case count.count > 1:
+			counts = append(counts, fmt.Sprintf("%d %s", count.count, count.plural))
+		}
+	}
+	return strings.Join(counts, ", ")
+}
+
+// slowMarker marks the testcases that took longer than the slow threshold
+const slowMarker = "🐢"
+
+// Body renders every suite as markdown, echoing each line to the console
+func Body(suites []junit.Suite, skipOk bool, console io.Writer) string {
+	body := ""
+	for _, suite := range suites {
+		body += Suite(suite, skipOk, console) + "\n"
+	}
+	return body
+}
+
+// clusterMinimum is the fewest failures with the same signature worth
+// calling out together
+const clusterMinimum = 2
+
+// Report renders every suite as markdown, preceded by notes for results that
+// were truncated, reports that could not be parsed or found, required suites
+// that are missing, a matrix of results for labeled builds, any failures
+// that share a signature, and the failures of each owner, and followed by
+// the commands that reproduce the failures, sections for flaky, quarantined,
+// slow, and slower than baseline testcases, and the coverage and benchmarks
+// of the run, echoing each line to the console
+func Report(report junit.Report, skipOk bool, console io.Writer) string {
+	return reportWith(report, skipOk, Body, console)
+}
+
+// reportWith renders the report like Report, laying out its suites with
+// layout
+func reportWith(report junit.Report, skipOk bool, layout func([]junit.Suite, bool, io.Writer) string, console io.Writer) string {
+	body := Truncated(report.Unparsed)
+	body += ParseErrors(report.ParseErrors)
+	body += MissingPaths(report.MissingPaths)
+	body += MissingSuites(report.MissingSuites)
+	body += Matrix(report)
+	body += Clusters(junit.ClusterFailures(report, clusterMinimum), console)
+	body += Owners(junit.FailuresByOwner(report), console)
+	body += layout(report.Suites, skipOk, console)
+	body += Reproduce(report.Suites, console)
+	body += Flaky(report.FlakyTests, console)
+	body += Quarantined(report.QuarantinedTests, console)
+	body += Slow(report.SlowTests, console)
+	body += Slower(report.SlowerTests, console)
+	body += Coverage(report.Coverage, console)
+	return body + Benchmarks(report.Benchmarks, console)
+}
+
+// ParseErrors renders a note for each report that could not be parsed, which
+// is logged rather than echoed to the console
+func ParseErrors(parseErrors []junit.ParseError) string {
+	if len(parseErrors) == 0 {
+		return ""
+	}
+
+	body := ""
+	for _, parseError := range parseErrors {
+		body += fmt.Sprintf("> ⚠️ could not parse report %s: %s\n", escape(parseError.File), escape(parseError.Message))
+	}
+	return body + "\n"
+}
+
+// Truncated renders a note that the results are incomplete because the run
+// ran out of time before the reports were parsed
+func Truncated(unparsed []string) string {
+	if len(unparsed) == 0 {
+		return ""
+	}
+
+	reports := "reports were"
+	if len(unparsed) == 1 {
+		reports = "report was"
+	}
+	return fmt.Sprintf("> ⏱️ results were truncated due to timeout, as %d %s not parsed\n\n", len(unparsed), reports)
+}
+
+// NoResults renders a note that no test results were found, such as when
+// reports are empty or were looked for in the wrong directory
+func NoResults() string {
+	return "> ⚠️ no test results found\n\n"
+}
+
+// MissingPaths renders a note for each report path that did not exist, which
+// is logged rather than echoed to the console
+func MissingPaths(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	body := ""
+	for _, path := range paths {
+		body += fmt.Sprintf("> ⚠️ no reports found at %s\n", escape(path))
+	}
+	return body + "\n"
+}
+
+// MissingSuites renders a note for each required suite that was not found,
+// which is logged rather than echoed to the console
+func MissingSuites(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	body := ""
+	for _, name := range names {
+		body += fmt.Sprintf("> ❌ required suite %s was not found\n", escape(name))
+	}
+	return body + "\n"
+}
+
+// Matrix renders a table of the results of each suite in each build of a
+// matrix, with a row for each suite and a column for each set of labels, or
+// nothing when the suites do not have at least two sets of labels
+func Matrix(report junit.Report) string {
+	labels := report.Labels()
+	if len(labels) < 2 {
+		return ""
+	}
+
+	var names []string
+	cells := map[string]map[string]junit.Summary{}
+	for _, suite := range report.Suites {
+		if _, ok := cells[suite.Name]; !ok {
+			names = append(names, suite.Name)
+			cells[suite.Name] = map[string]junit.Summary{}
+		}
+		summary := cells[suite.Name][suite.Label()]
+		summary.Add(suite)
+		cells[suite.Name][suite.Label()] = summary
+	}
+
+	body := "| Suite |"
+	separator := "|---|"
+	for _, label := range labels {
+		if label == "" {
+			label = "unlabeled"
+		}
+		body += " " + tableEscape(label) + " |"
+		separator += "---|"
+	}
+	body += "\n" + separator + "\n"
+
+	for _, name := range names {
+		body += "| " + tableEscape(name) + " |"
+		for _, label := range labels {
+			summary, ok := cells[name][label]
+			switch {
+			case !ok:
+				body += " — |"
+			case summary.Failed():
+				body += fmt.Sprintf(" ❌ %d of %d failed |", summary.Failures+summary.Errors, summary.Tests)
+			default:
+				body += fmt.Sprintf(" ✅ %d passed |", summary.Passed())
+			}
+		}
+		body += "\n"
+	}
+	return body + "\n"
+}
+
+// Totals renders a table of the counts and pass rate of the run, for comments
+// that leave out the results of each testcase
+func Totals(summary junit.Summary) string {
+	status := "✅"
+	if summary.Failed() {
+		status = "❌"
+	}
+	body := "| | Tests | Passed | Failed | Errors | Skipped | Pass rate |\n|---|---|---|---|---|---|---|\n"
+	body += fmt.Sprintf("| %s | %d | %d | %d | %d | %d | %.1f%% |\n", status, summary.Tests, summary.Passed(), summary.Failures, summary.Errors, summary.Skipped, summary.PassRate())
+	return body + "\n"
+}
+
+// tableEscape escapes text for a cell of a markdown table
+func tableEscape(text string) string {
+	return strings.ReplaceAll(escape(text), "|", "\\|")
+}
+
+// Clusters renders a summary of each group of failures sharing a signature,
+// or nothing when there are none
+func Clusters(clusters []junit.Cluster, console io.Writer) string {
+	if len(clusters) == 0 {
+		return ""
+	}
+
+	body := "### Common failures\n\n"
+	for _, cluster := range clusters {
+		message := fmt.Sprintf("%d tests failed with: %s", len(cluster.Cases), cluster.Message)
+		if cluster.Message == "" {
+			message = fmt.Sprintf("%d tests failed with: %s", len(cluster.Cases), cluster.Signature)
+		}
+		fmt.Fprintln(console, "# "+message)
+		body += "<details><summary>" + escape(message) + "</summary>\n\n"
+		for _, testcase := range cluster.Cases {
+			body += fmt.Sprintf("- %s\n", escape(testcase.DisplayId()))
+		}
+		body += "</details>\n"
+	}
+
+	return body + "\n"
+}
+
+// Owners renders the failing testcases of each owner, or nothing when no
+// failure has an owner. Owners are shown as code, so that rendering them
+// never mentions anyone.
+func Owners(groups []junit.OwnedFailures, console io.Writer) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	body := "### Failures by owner\n\n"
+	for _, group := range groups {
+		owner := group.Owner
+		if owner == "" {
+			owner = "no owner"
+		}
+		failures := "failures"
+		if len(group.Cases) == 1 {
+			failures = "failure"
+		}
+		fmt.Fprintf(console, "# %s: %d %s\n", owner, len(group.Cases), failures)
+		if group.Owner != "" {
+			owner = "<code>" + escape(owner) + "</code>"
+		}
+		body += fmt.Sprintf("<details><summary>%s (%d %s)</summary>\n\n", owner, len(group.Cases), failures)
+		for _, testcase := range group.Cases {
+			body += fmt.Sprintf("- %s\n", escape(testcase.DisplayId()))
+		}
+		body += "</details>\n"
+	}
+
+	return body + "\n"
+}
+
+// Mentions renders a line mentioning the owners of the failures, adding the
+// @ that github mentions need to owners without one, or nothing when no
+// failure has an owner
+func Mentions(groups []junit.OwnedFailures) string {
+	var mentions []string
+	for _, group := range groups {
+		if group.Owner != "" {
+			mentions = append(mentions, "@"+strings.TrimPrefix(group.Owner, "@"))
+		}
+	}
+	if len(mentions) == 0 {
+		return ""
+	}
+	return "cc " + strings.Join(mentions, " ") + "\n"
+}
+
+// Reproduce renders the commands that run the failing testcases of each
+// suite alone, or nothing when no failure has one
+func Reproduce(suites []junit.Suite, console io.Writer) string {
+	body := ""
+	for _, suite := range suites {
+		var commands []string
+		seen := map[string]bool{}
+		for _, testcase := range suite.Cases {
+			if testcase.Reproduce != "" && !seen[testcase.Reproduce] {
+				seen[testcase.Reproduce] = true
+				commands = append(commands, testcase.Reproduce)
+			}
+		}
+		if len(commands) == 0 {
+			continue
+		}
+
+		name := suite.Name
+		if label := suite.Label(); label != "" {
+			name += " [" + label + "]"
+		}
+		fmt.Fprintf(console, "# reproduce %s\n", name)
+		body += "**" + escape(name) + "**\n\n```sh\n"
+		for _, command := range commands {
+			fmt.Fprintln(console, command)
+			body += command + "\n"
+		}
+		body += "```\n\n"
+	}
+	if body == "" {
+		return ""
+	}
+	return "### How to reproduce\n\n" + body
+}
+
+// Flaky renders the testcases that both passed and failed during the run,
+// with their last failure, or nothing when there are none
+func Flaky(testcases []junit.Case, console io.Writer) string {
+	return failureSection("Flaky tests", "flaky", testcases, console)
+}
+
+// Quarantined renders the failures of quarantined testcases, or nothing when
+// there are none
+func Quarantined(testcases []junit.Case, console io.Writer) string {
+	return failureSection("Quarantined tests", "quarantined", testcases, console)
+}
+
+// failureSection renders a heading followed by each testcase's failure
+func failureSection(title string, label string, testcases []junit.Case, console io.Writer) string {
+	if len(testcases) == 0 {
+		return ""
+	}
+
+	message := fmt.Sprintf("# %s: %d", label, len(testcases))
+	body := fmt.Sprintf("### %s (%d)\n\n", title, len(testcases))
+	fmt.Fprintln(console, message)
+	for _, testcase := range testcases {
+		message := fmt.Sprintf("%s %s", label, testcase.DisplayId())
+		body += "<details><summary>" + escape(message) + "</summary>\n"
+		fmt.Fprintln(console, message)
+		body += failure(testcase, console)
+		body += "</details>\n"
+	}
+
+	return body + "\n"
+}
+
+// Slow renders a table of the testcases that took longer than the slow
+// threshold, or nothing when there are none
+func Slow(testcases []junit.Case, console io.Writer) string {
+	if len(testcases) == 0 {
+		return ""
+	}
+
+	fmt.Fprintf(console, "# slow: %d\n", len(testcases))
+	body := fmt.Sprintf("### %s Slow tests (%d)\n\n", slowMarker, len(testcases))
+	body += "| Test | Time |\n|---|---|\n"
+	for _, testcase := range testcases {
+		fmt.Fprintf(console, "slow %s in %ssec\n", testcase.DisplayId(), seconds(testcase.Time))
+		body += fmt.Sprintf("| %s | %ssec |\n", tableEscape(testcase.DisplayId()), seconds(testcase.Time))
+	}
+
+	return body + "\n"
+}
+
+// Slower renders a table of the testcases that took longer than in a baseline
+// run, or nothing when there are none
+func Slower(slowdowns []junit.Slowdown, console io.Writer) string {
+	if len(slowdowns) == 0 {
+		return ""
+	}
+
+	fmt.Fprintf(console, "# slower than baseline: %d\n", len(slowdowns))
+	body := fmt.Sprintf("### Slower than baseline (%d)\n\n", len(slowdowns))
+	body += "| Test | Baseline | Now | Change |\n|---|---|---|---|\n"
+	for _, slowdown := range slowdowns {
+		fmt.Fprintf(console, "slower %s in %ssec, was %ssec\n", slowdown.DisplayId(), seconds(slowdown.Time), seconds(slowdown.Baseline))
+		change := "+" + slowdown.Increase().String()
+		if slowdown.Baseline > 0 {
+			change = fmt.Sprintf("+%.0f%%", (slowdown.Time/slowdown.Baseline-1)*100)
+		}
+		body += fmt.Sprintf("| %s | %ssec | %ssec | %s |\n", escape(slowdown.DisplayId()), seconds(slowdown.Baseline), seconds(slowdown.Time), change)
+	}
+
+	return body + "\n"
+}
+
+// Coverage renders the percent of lines the run covered, with a table of its
+// packages, and how each changed from a baseline when compared to one, or
+// nothing when the coverage is not known
+func Coverage(coverage *junit.Coverage, console io.Writer) string {
+	if coverage == nil {
+		return ""
+	}
+
+	fmt.Fprintf(console, "# coverage: %.1f%%\n", coverage.Percent())
+	heading := fmt.Sprintf("Coverage: %.1f%%", coverage.Percent())
+	if change := coverageChange(coverage.Percent(), coverage.Baseline); change != "" {
+		heading += " (" + change + ")"
+	}
+	body := "### " + heading + "\n\n"
+	if len(coverage.Packages) == 0 {
+		return body
+	}
+
+	if coverage.Baseline != nil {
+		body += "| Package | Lines | Covered | Change |\n|---|---|---|---|\n"
+	} else {
+		body += "| Package | Lines | Covered |\n|---|---|---|\n"
+	}
+	for _, pkg := range coverage.Packages {
+		fmt.Fprintf(console, "coverage %s %.1f%% of %d lines\n", pkg.Name, pkg.Percent(), pkg.Lines)
+		row := fmt.Sprintf("| %s | %d | %.1f%% |", tableEscape(pkg.Name), pkg.Lines, pkg.Percent())
+		if coverage.Baseline != nil {
+			change := coverageChange(pkg.Percent(), pkg.Baseline)
+			if change == "" {
+				change = "new"
+			}
+			row += " " + change + " |"
+		}
+		body += row + "\n"
+	}
+
+	return body + "\n"
+}
+
+// coverageChange is how much the percent changed from the baseline, such as
+// +1.2%, or nothing without a baseline
+func coverageChange(percent float64, baseline *float64) string {
+	if baseline == nil {
+		return ""
+	}
+	change := percent - *baseline
+	switch {
+	case change >= 0.05:
+		return fmt.Sprintf("+%.1f%%", change)
+	case change <= -0.05:
+		return fmt.Sprintf("🔻 %.1f%%", change)
+	}
+	return "±0.0%"
+}
+
+// Benchmarks renders a table of the time and memory of each operation of the
+// benchmarks, with how their time changed from a baseline when compared to
+// one, marking regressions with ⚠️, or nothing when there are none
+func Benchmarks(benchmarks []junit.Benchmark, console io.Writer) string {
+	if len(benchmarks) == 0 {
+		return ""
+	}
+
+	compared, memory, regressions := false, false, 0
+	for _, benchmark := range benchmarks {
+		compared = compared || benchmark.Baseline != nil
+		memory = memory || benchmark.BytesPerOp != nil || benchmark.AllocsPerOp != nil
+		if benchmark.Regression {
+			regressions++
+		}
+	}
+
+	fmt.Fprintf(console, "# benchmarks: %d\n", len(benchmarks))
+	heading := fmt.Sprintf("Benchmarks (%d)", len(benchmarks))
+	if regressions > 0 {
+		heading = fmt.Sprintf("Benchmarks (%d, ⚠️ %d slower than baseline)", len(benchmarks), regressions)
+	}
+	body := "### " + heading + "\n\n"
+	header, separator := "| Benchmark | Time/op |", "|---|---|"
+	if memory {
+		header, separator = header+" Memory/op | Allocs/op |", separator+"---|---|"
+	}
+	if compared {
+		header, separator = header+" Baseline | Change |", separator+"---|---|"
+	}
+	body += header + "\n" + separator + "\n"
+
+	for _, benchmark := range benchmarks {
+		fmt.Fprintf(console, "benchmark %s %s/op\n", benchmark.Name, nanoseconds(benchmark.Nanoseconds))
+		row := fmt.Sprintf("| %s | %s |", tableEscape(benchmark.Name), nanoseconds(benchmark.Nanoseconds))
+		if memory {
+			row += " " + optionalNumber(benchmark.BytesPerOp, " B") + " | " + optionalNumber(benchmark.AllocsPerOp, "") + " |"
+		}
+		if compared {
+			if change, ok := benchmark.Change(); ok {
+				marker := ""
+				if benchmark.Regression {
+					marker = "⚠️ "
+				}
+				row += fmt.Sprintf(" %s | %s%+.1f%% |", nanoseconds(*benchmark.Baseline), marker, change)
+			} else {
+				row += " | new |"
+			}
+		}
+		body += row + "\n"
+	}
+
+	return body + "\n"
+}
+
+// nanoseconds formats a time in nanoseconds in the largest unit it has a
+// whole one of, keeping the precision of times shorter than a nanosecond
+// that time.Duration would round away
+func nanoseconds(ns float64) string {
+	switch {
+	case ns >= 1e9:
+		return fmt.Sprintf("%.2fs", ns/1e9)
+	case ns >= 1e6:
+		return fmt.Sprintf("%.2fms", ns/1e6)
+	case ns >= 1e3:
+		return fmt.Sprintf("%.2fµs", ns/1e3)
+	}
+	return fmt.Sprintf("%.2fns", ns)
+}
+
+func optionalNumber(value *float64, unit string) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64) + unit
+}
+
+// htmlEscaper entity-escapes the characters that would let text from a report
+// open or close tags in a comment, such as a </details> in a test name
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escape makes text from a report safe to place in the html of a comment,
+// where it still renders as written. Failure messages are shown in indented
+// code blocks, which need no escaping.
+func escape(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// seconds formats a time in seconds without trailing zeros
+func seconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', -1, 64)
+}
+
+// DefaultTimeFormat is the layout Started shows timestamps in when no other is
+// given
+const DefaultTimeFormat = "2006-01-02 15:04:05 MST"
+
+// Started describes when the earliest suite of the report started, in the
+// location and layout, or is empty when no suite has a timestamp. The location
+// defaults to utc.
+func Started(report junit.Report, location *time.Location, layout string) string {
+	started := report.Started()
+	if started.IsZero() {
+		return ""
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	if layout == "" {
+		layout = DefaultTimeFormat
+	}
+	return "Started " + started.In(location).Format(layout) + "\n\n"
+}
+
+// Assertions describes how many assertions the tests checked, or is empty when
+// the reports do not count them
+func Assertions(summary junit.Summary) string {
+	if summary.Assertions == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d assertions in %d tests\n\n", summary.Assertions, summary.Tests)
+}
+
+// Decorate adds a heading with the title and a link to the job to the body,
+// when they are set
+func Decorate(body string, title string, jobUrl string) string {
+	if jobUrl != "" {
+		body = fmt.Sprintf("[Build Url](%s)", jobUrl) + "\n\n" + body
+	}
+
+	if title != "" {
+		body = "## " + title + "\n\n" + body
+	}
+
+	return body
+}
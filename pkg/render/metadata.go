@@ -0,0 +1,121 @@
+package render
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// metadataVersion is the version of the metadata format, which is raised
+// whenever it changes in a way that older readers would misread
+const metadataVersion = 1
+
+// MaxMetadataLength is the most of a comment that its metadata may take up,
+// beyond which the ids of passing testcases are left out
+const MaxMetadataLength = 16 << 10
+
+// Metadata is the results of a run embedded in its comment, so that later
+// runs and other tools can read them back from the pull request without
+// storing them elsewhere
+type Metadata struct {
+	Version int `json:"version"`
+	// Key is the --comment-key of the comment, when it has one
+	Key     string        `json:"key,omitempty"`
+	Commit  string        `json:"commit,omitempty"`
+	RunId   string        `json:"run_id,omitempty"`
+	Summary junit.Summary `json:"summary"`
+	// Tests are the ids of the testcases of each status
+	Tests map[junit.Status][]string `json:"tests"`
+	// FailingSince is the commit each failing testcase has failed on since,
+	// for those that were already failing before the commit
+	FailingSince map[string]string `json:"failing_since,omitempty"`
+	// Truncated is whether the ids of passing testcases were left out to keep
+	// the metadata under MaxMetadataLength
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// metadataPattern matches the metadata embedded in a comment
+var metadataPattern = regexp.MustCompile(`<!-- xunit-to-github-results (\{.*\}) -->`)
+
+// NewMetadata is the metadata of the results of a run on the commit
+func NewMetadata(report junit.Report, commit string, runId string) Metadata {
+	metadata := Metadata{Version: metadataVersion, Commit: commit, RunId: runId, Summary: report.Summary, Tests: map[junit.Status][]string{}}
+	for _, suite := range report.Suites {
+		for _, testcase := range suite.Cases {
+			metadata.Tests[testcase.Status] = append(metadata.Tests[testcase.Status], testcase.Id())
+			if testcase.FailingSince != "" {
+				if metadata.FailingSince == nil {
+					metadata.FailingSince = map[string]string{}
+				}
+				metadata.FailingSince[testcase.Id()] = testcase.FailingSince
+			}
+		}
+	}
+	// testcases repeated across reports, such as those of shards, are listed
+	// once
+	for status, ids := range metadata.Tests {
+		sort.Strings(ids)
+		unique := ids[:0]
+		for i, id := range ids {
+			if i == 0 || id != ids[i-1] {
+				unique = append(unique, id)
+			}
+		}
+		metadata.Tests[status] = unique
+	}
+	return metadata
+}
+
+// Embed renders the metadata as an html comment, which github and other
+// providers do not show. The json is compact, and escapes the > of any -->
+// in test ids so that they cannot end the comment early.
+func (m Metadata) Embed() string {
+	data, err := json.Marshal(m)
+	if err == nil && len(data) > MaxMetadataLength {
+		truncated := m
+		truncated.Tests = map[junit.Status][]string{}
+		for status, ids := range m.Tests {
+			if status != junit.StatusPassed {
+				truncated.Tests[status] = ids
+			}
+		}
+		truncated.Truncated = true
+		data, err = json.Marshal(truncated)
+	}
+	if err != nil || len(data) > MaxMetadataLength {
+		return ""
+	}
+	return "\n<!-- xunit-to-github-results " + string(data) + " -->\n"
+}
+
+// ParseMetadata reads the metadata embedded in a comment, reporting whether
+// it has any
+func ParseMetadata(body string) (Metadata, bool) {
+	var metadata Metadata
+	match := metadataPattern.FindStringSubmatch(body)
+	if match == nil {
+		return metadata, false
+	}
+	if err := json.Unmarshal([]byte(match[1]), &metadata); err != nil || metadata.Version > metadataVersion {
+		return Metadata{}, false
+	}
+	return metadata, true
+}
+
+// Failing is the commit each testcase the run failed has failed on since,
+// which is the commit of the run for those it newly failed
+func (m Metadata) Failing() map[string]string {
+	failing := map[string]string{}
+	for _, status := range []junit.Status{junit.StatusFailed, junit.StatusError} {
+		for _, id := range m.Tests[status] {
+			since := m.FailingSince[id]
+			if since == "" {
+				since = m.Commit
+			}
+			failing[id] = since
+		}
+	}
+	return failing
+}
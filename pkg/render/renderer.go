@@ -0,0 +1,168 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// Renderer formats results for output
+type Renderer interface {
+	Render(w io.Writer, report junit.Report) error
+}
+
+// Options configures the renderers returned by New
+type Options struct {
+	SkipOk bool
+	Title  string
+	JobUrl string
+	// Location and TimeFormat are the timezone and layout the markdown
+	// renderer shows when the run started in
+	Location   *time.Location
+	TimeFormat string
+	// Assertions is whether the markdown and tap renderers include how many
+	// assertions the tests checked
+	Assertions bool
+	// Tree is whether the markdown renderer lays out testcases in a tree of
+	// their classnames
+	Tree bool
+	// Template is the text/template source used by the template renderer
+	Template string
+}
+
+var renderers = map[string]func(options Options) (Renderer, error){
+	"markdown": func(options Options) (Renderer, error) {
+		return Markdown{options.SkipOk, options.Title, options.JobUrl, options.Location, options.TimeFormat, options.Assertions, options.Tree}, nil
+	},
+	"tap":      func(options Options) (Renderer, error) { return TAP{options.SkipOk, options.Assertions}, nil },
+	"json":     func(options Options) (Renderer, error) { return JSON{}, nil },
+	"html":     func(options Options) (Renderer, error) { return HTMLPage{options.Title}, nil },
+	"template": func(options Options) (Renderer, error) { return NewTemplate(options) },
+}
+
+// Formats returns the names of the formats supported by New
+func Formats() []string {
+	var formats []string
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// New returns the renderer for the named format
+func New(format string, options Options) (Renderer, error) {
+	renderer, ok := renderers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %s, expected one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return renderer(options)
+}
+
+// Markdown renders results as a pull request comment
+type Markdown struct {
+	SkipOk     bool
+	Title      string
+	JobUrl     string
+	Location   *time.Location
+	TimeFormat string
+	Assertions bool
+	Tree       bool
+}
+
+// Body renders the report as the body of a comment, without its title, job
+// url, or when the run started, echoing each line to the console
+func (m Markdown) Body(report junit.Report, console io.Writer) string {
+	if m.Tree {
+		return reportWith(report, m.SkipOk, Tree, console)
+	}
+	return Report(report, m.SkipOk, console)
+}
+
+func (m Markdown) Render(w io.Writer, report junit.Report) error {
+	body := m.Body(report, ioutil.Discard)
+	if body == "" {
+		return nil
+	}
+	header := Started(report, m.Location, m.TimeFormat)
+	if m.Assertions {
+		header += Assertions(report.Summary)
+	}
+	_, err := io.WriteString(w, Decorate(header+body, m.Title, m.JobUrl))
+	return err
+}
+
+// TAP renders results as tap-like console output
+type TAP struct {
+	SkipOk     bool
+	Assertions bool
+}
+
+func (t TAP) Render(w io.Writer, report junit.Report) error {
+	Report(report, t.SkipOk, w)
+	if t.Assertions && report.Assertions > 0 {
+		fmt.Fprintf(w, "# assertions: %d\n", report.Assertions)
+	}
+	return nil
+}
+
+// JSON renders results as indented json
+type JSON struct{}
+
+func (JSON) Render(w io.Writer, report junit.Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// HTMLPage renders results as a standalone html page
+type HTMLPage struct {
+	Title string
+}
+
+func (h HTMLPage) Render(w io.Writer, report junit.Report) error {
+	html, err := HTML(h.Title, report.Summary, report.Suites)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, html)
+	return err
+}
+
+// Template renders results with a text/template, which is executed with the
+// Title and JobUrl along with the report, so that its Summary, Suites, and
+// helpers such as Slowest are available
+type Template struct {
+	Title    string
+	JobUrl   string
+	Template *template.Template
+}
+
+func NewTemplate(options Options) (Renderer, error) {
+	if options.Template == "" {
+		return nil, fmt.Errorf("the template output format requires a template")
+	}
+
+	tmpl, err := template.New("output").Funcs(template.FuncMap{
+		"trim": strings.TrimSpace,
+	}).Parse(options.Template)
+	if err != nil {
+		return nil, err
+	}
+	return Template{options.Title, options.JobUrl, tmpl}, nil
+}
+
+func (t Template) Render(w io.Writer, report junit.Report) error {
+	return t.Template.Execute(w, struct {
+		junit.Report
+		Title  string
+		JobUrl string
+	}{report, t.Title, t.JobUrl})
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiUnderline = "\x1b[4m"
+	ansiYellow    = "\x1b[33m"
+	ansiMagenta   = "\x1b[35m"
+	ansiCyan      = "\x1b[36m"
+)
+
+var (
+	ansiPattern    = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	summaryPattern = regexp.MustCompile(`^<details><summary>(.*)</summary>(</details>)?$`)
+	imagePattern   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	linkPattern    = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+	boldPattern    = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	codePattern    = regexp.MustCompile("`([^`]+)`")
+	tagPattern     = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// Terminal renders markdown, such as the body of a comment, for a terminal,
+// approximating how github displays it. Collapsed details are shown expanded
+// beneath their summary. Escapes for bold text and colors are left out unless
+// color is set.
+func Terminal(markdown string, color bool) string {
+	var out []string
+	var table []string
+	fenced := false
+	flushTable := func() {
+		if len(table) > 0 {
+			out = append(out, terminalTable(table)...)
+			table = nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(markdown, "\n"), "\n") {
+		if strings.HasPrefix(line, "|") && !fenced {
+			table = append(table, line)
+			continue
+		}
+		flushTable()
+
+		switch {
+		case strings.HasPrefix(line, "```"):
+			fenced = !fenced
+		case fenced:
+			out = append(out, "    "+ansiDim+line+ansiReset)
+		case strings.HasPrefix(line, "    "):
+			out = append(out, "    "+ansiDim+line[4:]+ansiReset)
+		case summaryPattern.MatchString(line):
+			match := summaryPattern.FindStringSubmatch(line)
+			marker := "▾ "
+			if match[2] != "" {
+				marker = "▸ "
+			}
+			out = append(out, marker+terminalInline(match[1]))
+		case line == "</details>":
+		case strings.HasPrefix(line, "# "), strings.HasPrefix(line, "## "):
+			heading := strings.TrimLeft(line, "# ")
+			out = append(out, ansiBold+ansiMagenta+ansiUnderline+terminalInline(heading)+ansiReset)
+		case strings.HasPrefix(line, "### "):
+			out = append(out, ansiBold+ansiCyan+terminalInline(line[4:])+ansiReset)
+		case strings.HasPrefix(line, "> "):
+			out = append(out, ansiYellow+"│ "+ansiReset+terminalInline(line[2:]))
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			out = append(out, "• "+terminalInline(line[2:]))
+		default:
+			out = append(out, terminalInline(line))
+		}
+	}
+	flushTable()
+
+	rendered := strings.Join(out, "\n") + "\n"
+	if !color {
+		rendered = ansiPattern.ReplaceAllString(rendered, "")
+	}
+	return rendered
+}
+
+// terminalInline renders the links, images, bold text, code, and html
+// entities within a line, dropping any other html tags
+func terminalInline(line string) string {
+	line = imagePattern.ReplaceAllString(line, "[image: $1]")
+	line = linkPattern.ReplaceAllString(line, ansiUnderline+"$1"+ansiReset+ansiDim+" ($2)"+ansiReset)
+	line = boldPattern.ReplaceAllString(line, ansiBold+"$1"+ansiReset)
+	line = codePattern.ReplaceAllString(line, ansiCyan+"$1"+ansiReset)
+	line = tagPattern.ReplaceAllString(line, "")
+	return html.UnescapeString(line)
+}
+
+// terminalTable renders the rows of a markdown table with its columns
+// aligned, leaving out the row that separates the header
+func terminalTable(lines []string) []string {
+	var rows [][]string
+	var widths []int
+	for i, line := range lines {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		if i == 1 && strings.Trim(strings.Join(cells, ""), "-: ") == "" {
+			continue
+		}
+
+		row := make([]string, len(cells))
+		for j, cell := range cells {
+			row[j] = terminalInline(strings.TrimSpace(cell))
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := terminalWidth(row[j]); width > widths[j] {
+				widths[j] = width
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var out []string
+	for i, row := range rows {
+		var cells []string
+		for j, cell := range row {
+			padded := cell + strings.Repeat(" ", widths[j]-terminalWidth(cell))
+			if i == 0 {
+				padded = ansiBold + padded + ansiReset
+			}
+			cells = append(cells, padded)
+		}
+		out = append(out, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	return out
+}
+
+// terminalWidth is how many columns the text takes up, ignoring escapes
+func terminalWidth(text string) int {
+	return utf8.RuneCountInString(ansiPattern.ReplaceAllString(text, ""))
+}
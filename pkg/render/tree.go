@@ -0,0 +1,125 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// treeNode is a package, namespace, or class of a classname tree, with the
+// testcases whose classname ends at it
+type treeNode struct {
+	name     string
+	children []*treeNode
+	index    map[string]*treeNode
+	cases    []int
+	summary  junit.Summary
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	if child, ok := n.index[name]; ok {
+		return child
+	}
+	if n.index == nil {
+		n.index = map[string]*treeNode{}
+	}
+	child := &treeNode{name: name}
+	n.index[name] = child
+	n.children = append(n.children, child)
+	return child
+}
+
+// compact joins each node that has a single child and no testcases of its own
+// with that child, so that com → example → service becomes com.example.service
+func (n *treeNode) compact(separator string) {
+	for _, child := range n.children {
+		for len(child.children) == 1 && len(child.cases) == 0 {
+			grandchild := child.children[0]
+			child.name += separator + grandchild.name
+			child.children = grandchild.children
+			child.cases = grandchild.cases
+		}
+		child.compact(separator)
+	}
+}
+
+// TreeSuite renders the suite as markdown like Suite, with its testcases in a
+// tree of collapsible sections built from their dotted or slashed classnames,
+// which scales better than a flat list for suites with thousands of tests
+func TreeSuite(testsuite junit.Suite, skipOk bool, console io.Writer) string {
+	root := &treeNode{}
+	separator := "."
+	for i, testcase := range testsuite.Cases {
+		node := root
+		summary := caseSummary(testcase)
+		if testcase.Classname != "" {
+			parts := strings.Split(testcase.Classname, ".")
+			if strings.Contains(testcase.Classname, "/") {
+				separator = "/"
+				parts = strings.Split(testcase.Classname, "/")
+			}
+			for _, part := range parts {
+				node = node.child(part)
+				node.summary.Add(junit.Suite{Summary: summary})
+			}
+		}
+		node.cases = append(node.cases, i)
+	}
+	root.compact(separator)
+
+	body := suiteHeading(testsuite, skipOk, console)
+	return body + treeNodeBody(root, testsuite, skipOk, console)
+}
+
+// treeNodeBody renders the testcases of the node followed by a collapsible
+// section for each child, leaving out the sections with nothing in them
+func treeNodeBody(node *treeNode, testsuite junit.Suite, skipOk bool, console io.Writer) string {
+	body := ""
+	cases := make([]junit.Case, len(node.cases))
+	for i, index := range node.cases {
+		cases[i] = testsuite.Cases[index]
+	}
+	names := junit.Suite{Cases: cases}.Names()
+	for i, index := range node.cases {
+		body += testcaseLine(index, names[i], testsuite.Cases[index], skipOk, console)
+	}
+
+	for _, child := range node.children {
+		if skipOk && !child.summary.Failed() {
+			continue
+		}
+
+		tests := "tests"
+		if child.summary.Tests == 1 {
+			tests = "test"
+		}
+		message := fmt.Sprintf("%s (%d %s)", child.name, child.summary.Tests, tests)
+		if child.summary.Failed() {
+			message = fmt.Sprintf("❌ %s (%d of %d failed)", child.name, child.summary.Failures+child.summary.Errors, child.summary.Tests)
+		}
+		fmt.Fprintln(console, "# "+message)
+		body += "<details><summary>" + escape(message) + "</summary>\n\n"
+		body += treeNodeBody(child, testsuite, skipOk, console)
+		body += "</details>\n"
+	}
+	return body
+}
+
+// caseSummary counts the testcase
+func caseSummary(testcase junit.Case) junit.Summary {
+	suite := junit.Suite{Cases: []junit.Case{testcase}}
+	suite.Count()
+	return suite.Summary
+}
+
+// Tree renders every suite as markdown with TreeSuite, echoing each line to
+// the console
+func Tree(suites []junit.Suite, skipOk bool, console io.Writer) string {
+	body := ""
+	for _, suite := range suites {
+		body += TreeSuite(suite, skipOk, console) + "\n"
+	}
+	return body
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"reflect"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// commentPayload is a comment prepared by --export, holding everything the
+// post command needs to send it later without the reports
+type commentPayload struct {
+	Target  commentTarget  `json:"target"`
+	Body    string         `json:"body"`
+	Summary *junit.Summary `json:"summary,omitempty"`
+	Passed  bool           `json:"passed"`
+}
+
+// commentTarget is where a comment is posted. Credentials are left out, as
+// they are provided by whatever runs the post command, which also decides
+// where the comment is posted rather than trusting the target.
+type commentTarget struct {
+	Provider         string `json:"provider"`
+	RepositorySlug   string `json:"repository_slug,omitempty"`
+	PullRequestId    int    `json:"pull_request_id,omitempty"`
+	Title            string `json:"title,omitempty"`
+	JobUrl           string `json:"job_url,omitempty"`
+	GitlabUrl        string `json:"gitlab_url,omitempty"`
+	GiteaUrl         string `json:"gitea_url,omitempty"`
+	AzureDevopsUrl   string `json:"azure_devops_url,omitempty"`
+	GerritUrl        string `json:"gerrit_url,omitempty"`
+	GerritLabel      string `json:"gerrit_label,omitempty"`
+	BuildkiteContext string `json:"buildkite_context,omitempty"`
+	BitbucketReport  bool   `json:"bitbucket_report,omitempty"`
+	CommentKey       string `json:"comment_key,omitempty"`
+}
+
+func newCommentTarget(options *commentOptions) commentTarget {
+	provider := options.Provider
+	if provider == "" {
+		provider = detectProvider()
+	}
+
+	return commentTarget{
+		Provider:         provider,
+		RepositorySlug:   options.RepositorySlug,
+		PullRequestId:    options.PullRequestId,
+		Title:            options.Title,
+		JobUrl:           options.JobUrl,
+		GitlabUrl:        options.GitlabUrl,
+		GiteaUrl:         options.GiteaUrl,
+		AzureDevopsUrl:   options.AzureDevopsUrl,
+		GerritUrl:        options.GerritUrl,
+		GerritLabel:      options.GerritLabel,
+		BuildkiteContext: options.BuildkiteContext,
+		BitbucketReport:  options.BitbucketReport,
+		CommentKey:       options.CommentKey,
+	}
+}
+
+// apply fills in the title and comment key from the target when they were not
+// set on the command line. Where the comment is posted, and through which
+// provider, is only taken from the flags and environment of the post command,
+// as the payload may have been written by an untrusted job, such as the tests
+// of a pull request from a fork, which could otherwise post to another pull
+// request or send the credentials of the post command to a host of its
+// choosing. The rest of the target is ignored, with a warning for the fields
+// that differ from where the comment is posted.
+func (t commentTarget) apply(options *commentOptions, flags *flag.FlagSet) {
+	set := commandLineFlags(flags)
+	if !set["title"] && t.Title != "" {
+		options.Title = t.Title
+	}
+	if !set["comment-key"] && t.CommentKey != "" {
+		options.CommentKey = t.CommentKey
+	}
+
+	trusted := newCommentTarget(options)
+	fields := []struct {
+		name             string
+		payload, trusted interface{}
+	}{
+		{"provider", t.Provider, trusted.Provider},
+		{"repository-slug", t.RepositorySlug, trusted.RepositorySlug},
+		{"pull-request-id", t.PullRequestId, trusted.PullRequestId},
+		{"job-url", t.JobUrl, trusted.JobUrl},
+		{"gitlab-url", t.GitlabUrl, trusted.GitlabUrl},
+		{"gitea-url", t.GiteaUrl, trusted.GiteaUrl},
+		{"azure-devops-url", t.AzureDevopsUrl, trusted.AzureDevopsUrl},
+		{"gerrit-url", t.GerritUrl, trusted.GerritUrl},
+		{"gerrit-label", t.GerritLabel, trusted.GerritLabel},
+		{"buildkite-context", t.BuildkiteContext, trusted.BuildkiteContext},
+		{"bitbucket-report", t.BitbucketReport, trusted.BitbucketReport},
+	}
+	for _, field := range fields {
+		if !reflect.ValueOf(field.payload).IsZero() && field.payload != field.trusted {
+			logger.Warn("ignoring payload target", "field", field.name, "payload", field.payload, "using", field.trusted)
+		}
+	}
+}
+
+// exportComment writes the comment to path rather than posting it. The file
+// is written atomically, so an interrupted export never leaves a partial
+// payload to be posted.
+func exportComment(path string, options *commentOptions, summary *junit.Summary, passed bool, body string) error {
+	payload := commentPayload{
+		Target:  newCommentTarget(options),
+		Body:    body,
+		Summary: summary,
+		Passed:  passed,
+	}
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		return writeJSON(w, payload)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("comment exported", "file", path, "provider", payload.Target.Provider)
+	return nil
+}
+
+type postOptions struct {
+	Comment *commentOptions
+}
+
+func newPostFlags() (*flag.FlagSet, *postOptions) {
+	flags := flag.NewFlagSet("xunit-to-github post", flag.ExitOnError)
+	options := &postOptions{}
+	options.Comment = addCommentFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runPost posts a comment exported with --export, from a file or stdin
+func runPost(args []string) {
+	flags, options := newPostFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	data, err := readInput(flags.Args())
+	if err != nil {
+		logger.Fatal(exitParseError, "could not read payload", "error", err)
+	}
+
+	var payload commentPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		logger.Fatal(exitParseError, "could not decode payload", "error", err)
+	}
+	if payload.Body == "" {
+		return
+	}
+
+	payload.Target.apply(options.Comment, flags)
+	if _, err := postComment(ctx, options.Comment, payload.Summary, payload.Passed, payload.Body); err != nil {
+		logger.Fatal(exitPublishError, "could not post comment", "error", err)
+	}
+}
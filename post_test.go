@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestCommentTargetApply(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	options := addCommentFlags(flags)
+	if err := flags.Parse([]string{"--provider", "github", "--repository-slug", "owner/repo", "--pull-request-id", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := commentTarget{
+		Provider:       "gitlab",
+		RepositorySlug: "fork/repo",
+		PullRequestId:  9,
+		Title:          "Unit tests",
+		JobUrl:         "https://example.com/job",
+		GitlabUrl:      "https://example.com",
+		GiteaUrl:       "https://example.com",
+		AzureDevopsUrl: "https://example.com",
+		GerritUrl:      "https://example.com",
+		CommentKey:     "unit",
+	}
+	target.apply(options, flags)
+
+	if options.Provider != "github" || options.RepositorySlug != "owner/repo" || options.PullRequestId != 2 {
+		t.Errorf("target = %s %s #%d, want github owner/repo #2", options.Provider, options.RepositorySlug, options.PullRequestId)
+	}
+	if options.JobUrl != "" || options.GitlabUrl != "" || options.GiteaUrl != "" || options.AzureDevopsUrl != "" || options.GerritUrl != "" {
+		t.Errorf("urls were taken from the payload: %+v", options)
+	}
+	if options.Title != "Unit tests" || options.CommentKey != "unit" {
+		t.Errorf("title and comment key = %q %q, want them from the payload", options.Title, options.CommentKey)
+	}
+}
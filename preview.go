@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+type previewOptions struct {
+	SkipOk         bool
+	Title          string
+	JobUrl         string
+	Output         string
+	TemplateFile   string
+	ShowAssertions bool
+	Tree           bool
+	Time           *timeOptions
+	Filter         *filterOptions
+	Parsing        *parsingOptions
+}
+
+func newPreviewFlags() (*flag.FlagSet, *previewOptions) {
+	flags := flag.NewFlagSet("xunit-to-github preview", flag.ExitOnError)
+	options := &previewOptions{}
+	flags.BoolVar(&options.SkipOk, "skip-ok", false, "skip-ok: Whether to skip ok tests or not")
+	flags.BoolVar(&options.Tree, "tree", false, "tree: Whether to lay out tests in a tree of collapsible sections built from their classnames, rather than a flat list")
+	flags.StringVar(&options.Title, "title", "", "title: A title for the comment")
+	flags.StringVar(&options.JobUrl, "job-url", "", "job-url: A url for the report")
+	addOutputFlags(flags, &options.Output, &options.TemplateFile, "markdown")
+	flags.BoolVar(&options.ShowAssertions, "show-assertions", false, "show-assertions: Whether to include how many assertions the tests checked, when the reports count them")
+	options.Time = addTimeFlags(flags)
+	options.Filter = addFilterFlags(flags)
+	options.Parsing = addParsingFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runPreview renders the comment for xml reports as it would roughly appear
+// on github, for iterating on templates and flags locally
+func runPreview(args []string) {
+	flags, options := newPreviewFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	if options.Output != "markdown" && options.Output != "template" {
+		logger.Fatal(exitConfigError, "invalid output", "error", fmt.Errorf("preview renders markdown or a template, not %s", options.Output))
+	}
+
+	location, err := options.Time.location()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid timezone", "error", err)
+	}
+
+	renderer, err := newRenderer(options.Output, options.TemplateFile, render.Options{SkipOk: options.SkipOk, Title: options.Title, JobUrl: options.JobUrl, Location: location, TimeFormat: options.Time.Format, Assertions: options.ShowAssertions, Tree: options.Tree})
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid output", "error", err)
+	}
+
+	filter, err := options.Filter.compile()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid filter", "error", err)
+	}
+
+	files, missing, err := options.Parsing.findFiles(flags.Args())
+	if err != nil {
+		logger.Fatal(exitParseError, "could not find reports", "error", err)
+	}
+
+	results, err := options.Parsing.parseFiles(ctx, files)
+	if err != nil {
+		logger.Fatal(exitParseError, "could not parse reports", "error", err)
+	}
+	results.MissingPaths = missing
+
+	var body bytes.Buffer
+	if err := renderer.Render(&body, junit.FilterReport(junit.MarkFlaky(results), filter)); err != nil {
+		logger.Fatal(exitConfigError, "could not render results", "error", err)
+	}
+	if body.Len() == 0 {
+		logger.Info("nothing would be posted")
+		return
+	}
+
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	fmt.Fprint(os.Stdout, render.Terminal(body.String(), color))
+}
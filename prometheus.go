@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func pushPrometheusMetrics(ctx context.Context, pushgatewayUrl string, job string, repositorySlug string, branch string, summary junit.Summary, testsuites []junit.Suite) error {
+	url := fmt.Sprintf("%s/metrics/job/%s", strings.TrimSuffix(pushgatewayUrl, "/"), pushgatewayLabel(job))
+	if repositorySlug != "" {
+		url += "/repo@base64/" + base64.RawURLEncoding.EncodeToString([]byte(repositorySlug))
+	}
+	if branch != "" {
+		url += "/branch@base64/" + base64.RawURLEncoding.EncodeToString([]byte(branch))
+	}
+
+	metrics := []struct {
+		name  string
+		help  string
+		value func(testsuite junit.Suite) float64
+	}{
+		{"xunit_tests_total", "Number of tests in the suite", func(t junit.Suite) float64 { return float64(t.Tests) }},
+		{"xunit_failures_total", "Number of failed tests in the suite", func(t junit.Suite) float64 { return float64(t.Failures) }},
+		{"xunit_errors_total", "Number of errored tests in the suite", func(t junit.Suite) float64 { return float64(t.Errors) }},
+		{"xunit_skipped_total", "Number of skipped tests in the suite", func(t junit.Suite) float64 { return float64(t.Skipped) }},
+		{"xunit_duration_seconds", "Duration of the suite in seconds", func(t junit.Suite) float64 { return t.Time }},
+	}
+
+	var b strings.Builder
+	for _, metric := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		for _, testsuite := range testsuites {
+			fmt.Fprintf(&b, "%s{suite=\"%s\"} %g\n", metric.name, escapePrometheusLabel(testsuite.Name), metric.value(testsuite))
+		}
+	}
+
+	fmt.Fprintf(&b, "# HELP xunit_pass_rate Percentage of executed tests that passed\n# TYPE xunit_pass_rate gauge\nxunit_pass_rate %g\n", summary.PassRate())
+
+	headers := map[string]string{
+		"Content-Type": "text/plain; version=0.0.4",
+	}
+
+	_, err := sendRequest(ctx, "PUT", url, headers, []byte(b.String()), 0)
+	return err
+}
+
+func pushgatewayLabel(value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return value
+}
+
+func escapePrometheusLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	return strings.Replace(value, `"`, `\"`, -1)
+}
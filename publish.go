@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+const defaultHTTPTimeout = 30 * time.Second
+
+// httpClient sends every request to publishers. It may be replaced, or given
+// a transport wrapping its own, to add middleware such as tracing, auth, or
+// recording, or to send requests to an httptest server; parsing flags only
+// changes its timeout.
+var httpClient = newHTTPClient(defaultHTTPTimeout)
+
+// newHTTPClient returns a client that fails requests taking longer than the
+// timeout, or never when the timeout is 0. Connecting and negotiating tls have
+// their own shorter timeouts so unreachable hosts fail quickly.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: time.Second,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// setHTTPTimeout applies the --http-timeout flag to httpClient, keeping
+// whichever transport it has
+func setHTTPTimeout(timeout time.Duration) {
+	httpClient.Timeout = timeout
+	if transport, ok := httpClient.Transport.(*http.Transport); ok {
+		transport.ResponseHeaderTimeout = timeout
+	}
+}
+
+// sendJSON sends the payload as json and errors unless the response has the
+// expected status code, or any 2xx status code when expectedStatus is 0
+func sendJSON(ctx context.Context, method string, url string, headers map[string]string, payload interface{}, expectedStatus int) ([]byte, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sendRequest(ctx, method, url, headers, data, expectedStatus)
+}
+
+func sendRequest(ctx context.Context, method string, url string, headers map[string]string, data []byte, expectedStatus int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := ioutil.ReadAll(resp.Body)
+	if expectedStatus == 0 && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return responseBody, nil
+	}
+
+	if resp.StatusCode != expectedStatus {
+		return responseBody, fmt.Errorf("err: %s", string(responseBody))
+	}
+
+	return responseBody, nil
+}
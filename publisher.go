@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// Publisher sends the results of a run somewhere, such as a pull request
+// comment or a chat channel, along with the rendered markdown body
+type Publisher interface {
+	Publish(ctx context.Context, results junit.Report, body string) error
+}
+
+// PublisherFunc adapts a function into a Publisher
+type PublisherFunc func(ctx context.Context, results junit.Report, body string) error
+
+func (f PublisherFunc) Publish(ctx context.Context, results junit.Report, body string) error {
+	return f(ctx, results, body)
+}
+
+// publishSession is the state shared by the publishers of a run
+type publishSession struct {
+	options *reportOptions
+	history historyRun
+	// location is the timezone comments show when the run started in
+	location *time.Location
+	// reportUrl is where the full report was uploaded, when it was
+	reportUrl string
+
+	// commentUrl is set once the comment is posted, so that later publishers
+	// can link to it
+	commentUrl string
+}
+
+type publisherRegistration struct {
+	name string
+	new  func(session *publishSession) Publisher
+}
+
+// publisherRegistry lists the publishers by their --publish name in the order
+// they run. Each constructor returns nil when the publisher is not configured.
+var publisherRegistry = []publisherRegistration{
+	{"history", func(session *publishSession) Publisher {
+		options := session.options
+		if options.HistoryDb == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := (historyDB{location: options.HistoryDb}).recordRun(ctx, session.history, results.Summary, results.Suites); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "history")
+			return nil
+		})
+	}},
+	{"elasticsearch", func(session *publishSession) Publisher {
+		options := session.options
+		if options.ElasticsearchUrl == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := indexTestResults(ctx, options.ElasticsearchUrl, options.ElasticsearchIndex, session.history, results.Suites); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "elasticsearch")
+			return nil
+		})
+	}},
+	{"comment", func(session *publishSession) Publisher {
+		options := session.options
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if options.Export != "" {
+				return exportComment(options.Export, options.Comment, &results.Summary, options.Thresholds.Passed(results.Summary), body)
+			}
+			if options.CommentPerSuite {
+				commentUrl, err := postSuiteComments(ctx, session, results)
+				session.commentUrl = commentUrl
+				return err
+			}
+			commentUrl, err := postComment(ctx, options.Comment, &results.Summary, options.Thresholds.Passed(results.Summary), body)
+			session.commentUrl = commentUrl
+			return err
+		})
+	}},
+	{"checks", func(session *publishSession) Publisher {
+		options := session.options
+		if options.CheckRun == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			return publishCheckRun(ctx, options, results, body)
+		})
+	}},
+	{"slack", func(session *publishSession) Publisher {
+		options := session.options
+		if options.SlackWebhookUrl == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if options.SlackOnlyOnFailure && !results.Summary.Failed() {
+				return nil
+			}
+			if err := postSlackMessage(ctx, options.SlackWebhookUrl, options.Comment.Title, results.Summary, session.commentUrl, options.Comment.JobUrl); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "slack")
+			return nil
+		})
+	}},
+	{"teams", func(session *publishSession) Publisher {
+		options := session.options
+		if options.TeamsWebhookUrl == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := postTeamsMessage(ctx, options.TeamsWebhookUrl, options.Comment.Title, results.Summary, results.Suites, session.commentUrl, options.Comment.JobUrl); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "teams")
+			return nil
+		})
+	}},
+	{"discord", func(session *publishSession) Publisher {
+		options := session.options
+		if options.DiscordWebhookUrl == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := postDiscordMessage(ctx, options.DiscordWebhookUrl, options.Comment.Title, results.Summary, results.Suites, session.commentUrl, options.Comment.JobUrl); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "discord")
+			return nil
+		})
+	}},
+	{"webhook", func(session *publishSession) Publisher {
+		options := session.options
+		if options.WebhookUrl == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := postWebhook(ctx, options.WebhookUrl, options.WebhookHeaders, options.WebhookSecret, results.Summary, results.Suites, body); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "webhook")
+			return nil
+		})
+	}},
+	{"prometheus", func(session *publishSession) Publisher {
+		options := session.options
+		if options.PushgatewayUrl == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := pushPrometheusMetrics(ctx, options.PushgatewayUrl, options.PushgatewayJob, options.Comment.RepositorySlug, options.Branch, results.Summary, results.Suites); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "prometheus")
+			return nil
+		})
+	}},
+	{"datadog", func(session *publishSession) Publisher {
+		options := session.options
+		datadogApiKey := os.Getenv("DD_API_KEY")
+		if !options.Datadog || datadogApiKey == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			var tags []string
+			if options.Comment.RepositorySlug != "" {
+				tags = append(tags, "repo:"+options.Comment.RepositorySlug)
+			}
+			if options.Branch != "" {
+				tags = append(tags, "branch:"+options.Branch)
+			}
+			if options.Commit != "" {
+				tags = append(tags, "commit:"+options.Commit)
+			}
+			if options.Comment.PullRequestId != 0 {
+				tags = append(tags, fmt.Sprintf("pr:%d", options.Comment.PullRequestId))
+			}
+
+			if err := postDatadog(ctx, options.DatadogSite, datadogApiKey, tags, results.Summary, results.Suites); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "datadog")
+			return nil
+		})
+	}},
+	{"opentelemetry", func(session *publishSession) Publisher {
+		options := session.options
+		if options.OtlpEndpoint == "" {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if err := exportSpans(ctx, options.OtlpEndpoint, options.Comment.Title, results.Summary, results.Suites); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "opentelemetry")
+			return nil
+		})
+	}},
+	{"email", func(session *publishSession) Publisher {
+		options := session.options
+		if options.SmtpHost == "" || len(options.EmailTo) == 0 {
+			return nil
+		}
+		return PublisherFunc(func(ctx context.Context, results junit.Report, body string) error {
+			if options.EmailOnlyOnFailure && !results.Summary.Failed() {
+				return nil
+			}
+			if err := sendEmail(ctx, options.SmtpHost, options.SmtpUsername, options.EmailFrom, options.EmailTo, options.Comment.Title, results.Summary, results.Suites); err != nil {
+				return err
+			}
+			logger.Info("results published", "publisher", "email")
+			return nil
+		})
+	}},
+}
+
+func publisherNames() []string {
+	var names []string
+	for _, registration := range publisherRegistry {
+		names = append(names, registration.name)
+	}
+	return names
+}
+
+type namedPublisher struct {
+	name      string
+	publisher Publisher
+}
+
+// selectPublishers returns the publishers named by --publish in registry
+// order, or every configured publisher when none are named. Naming an unknown
+// or unconfigured publisher is an error.
+func selectPublishers(session *publishSession, names []string) ([]namedPublisher, error) {
+	selected := map[string]bool{}
+	for _, name := range names {
+		found := false
+		for _, registration := range publisherRegistry {
+			if registration.name == name {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown publisher %s, expected one of %s", name, strings.Join(publisherNames(), ", "))
+		}
+		selected[name] = true
+	}
+
+	var publishers []namedPublisher
+	for _, registration := range publisherRegistry {
+		if len(names) > 0 && !selected[registration.name] {
+			continue
+		}
+
+		publisher := registration.new(session)
+		if publisher == nil {
+			if selected[registration.name] {
+				return nil, fmt.Errorf("publisher %s is not configured", registration.name)
+			}
+			continue
+		}
+		publishers = append(publishers, namedPublisher{registration.name, publisher})
+	}
+	return publishers, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+)
+
+// interrupted is set once the process receives SIGINT or SIGTERM
+var interrupted int32
+
+// interruptContext returns a context that is canceled when the process
+// receives SIGINT or SIGTERM, so that parsing stops and in-flight requests
+// are abandoned rather than leaving a half-posted series of comments. A
+// second signal exits immediately.
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			atomic.StoreInt32(&interrupted, 1)
+			logger.Warn("interrupted, canceling in-flight requests", "signal", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// exit exits with the code, or with exitInterrupted once the process has been
+// interrupted, since whatever failed most likely did so because of it
+func exit(code int) {
+	if atomic.LoadInt32(&interrupted) == 1 {
+		code = exitInterrupted
+	}
+	os.Exit(code)
+}
+
+// writeFileAtomic writes a file with write, by writing a temporary file
+// alongside it and renaming it into place once write succeeds, so that a
+// failed or interrupted write never leaves a partial file behind
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func postSlackMessage(ctx context.Context, webhookUrl string, title string, summary junit.Summary, commentUrl string, jobUrl string) error {
+	if title == "" {
+		title = "Test results"
+	}
+
+	status := ":white_check_mark: passed"
+	if summary.Failed() {
+		status = ":x: failed"
+	}
+
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]string{"type": "plain_text", "text": title},
+		},
+		{
+			"type": "section",
+			"fields": []map[string]string{
+				{"type": "mrkdwn", "text": fmt.Sprintf("*Status*\n%s", status)},
+				{"type": "mrkdwn", "text": fmt.Sprintf("*Pass rate*\n%.1f%%", summary.PassRate())},
+				{"type": "mrkdwn", "text": fmt.Sprintf("*Tests*\n%d", summary.Tests)},
+				{"type": "mrkdwn", "text": fmt.Sprintf("*Failures*\n%d", summary.Failures)},
+				{"type": "mrkdwn", "text": fmt.Sprintf("*Errors*\n%d", summary.Errors)},
+				{"type": "mrkdwn", "text": fmt.Sprintf("*Skipped*\n%d", summary.Skipped)},
+			},
+		},
+	}
+
+	links := ""
+	if commentUrl != "" {
+		links += fmt.Sprintf("<%s|View comment>", commentUrl)
+	}
+	if jobUrl != "" {
+		if links != "" {
+			links += " | "
+		}
+		links += fmt.Sprintf("<%s|View job>", jobUrl)
+	}
+	if links != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type":     "context",
+			"elements": []map[string]string{{"type": "mrkdwn", "text": links}},
+		})
+	}
+
+	message := map[string]interface{}{
+		"text":   fmt.Sprintf("%s: %s, %d/%d tests passed", title, status, summary.Passed(), summary.Tests),
+		"blocks": blocks,
+	}
+
+	_, err := sendJSON(ctx, "POST", webhookUrl, nil, message, 200)
+	return err
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+	"github.com/josegonzalez/go-xunit-to-github/pkg/render"
+)
+
+type storageTarget struct {
+	Scheme    string
+	Bucket    string
+	Prefix    string
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Token     string
+}
+
+func parseStorageTarget(uploadUrl string, endpoint string) (storageTarget, error) {
+	var target storageTarget
+	u, err := url.Parse(uploadUrl)
+	if err != nil {
+		return target, err
+	}
+
+	target.Scheme = u.Scheme
+	target.Bucket = u.Host
+	target.Prefix = strings.Trim(u.Path, "/")
+	if target.Bucket == "" {
+		return target, fmt.Errorf("invalid upload url, missing bucket: %s", uploadUrl)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		target.Region = getenvDefault("AWS_REGION", getenvDefault("AWS_DEFAULT_REGION", "us-east-1"))
+		target.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		target.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		target.Token = os.Getenv("AWS_SESSION_TOKEN")
+		target.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", target.Region)
+	case "gs":
+		target.Region = "auto"
+		target.AccessKey = os.Getenv("GCS_HMAC_ACCESS_KEY_ID")
+		target.SecretKey = os.Getenv("GCS_HMAC_SECRET")
+		target.Endpoint = "https://storage.googleapis.com"
+	default:
+		return target, fmt.Errorf("unsupported upload url scheme, expected s3 or gs: %s", uploadUrl)
+	}
+
+	if endpoint != "" {
+		target.Endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+
+	if target.AccessKey == "" || target.SecretKey == "" {
+		return target, fmt.Errorf("missing credentials for %s upload", u.Scheme)
+	}
+
+	return target, nil
+}
+
+// maxPresignExpiry is the longest that sigv4 presigned urls may be valid for
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// validatePresignExpiry checks that presigned urls can be valid for expiry
+func validatePresignExpiry(expiry time.Duration) error {
+	if expiry <= 0 || expiry > maxPresignExpiry {
+		return fmt.Errorf("presign expiry must be greater than 0 and at most %s, got %s", maxPresignExpiry, expiry)
+	}
+	return nil
+}
+
+// uploadReports uploads report.html and report.json beneath run, such as the
+// ci run id or commit, so that the reports of other runs uploaded to the same
+// prefix are not overwritten, returning the url of the html report
+func uploadReports(ctx context.Context, uploadUrl string, endpoint string, expiry time.Duration, run string, title string, summary junit.Summary, testsuites []junit.Suite, body string) (string, error) {
+	target, err := parseStorageTarget(uploadUrl, endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(junit.Report{Summary: summary, Suites: testsuites, Body: body})
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := uploadObject(ctx, target, path.Join(run, "report.json"), "application/json", data, expiry); err != nil {
+		return "", err
+	}
+
+	html, err := render.HTML(title, summary, testsuites)
+	if err != nil {
+		return "", err
+	}
+
+	return uploadObject(ctx, target, path.Join(run, "report.html"), "text/html; charset=utf-8", []byte(html), expiry)
+}
+
+// maxAttachmentSize is the largest attachment uploaded, so that a stray video
+// or heap dump does not slow down every run
+const maxAttachmentSize = 20 << 20
+
+// uploadAttachments uploads the attachments of the failed testcases beneath
+// attachments/ in the bucket, setting the urls they are shown or linked with.
+// Relative paths are read from the current directory, then from root.
+// Attachments that cannot be read or uploaded are warned about and left
+// without a url rather than failing the run.
+func uploadAttachments(ctx context.Context, uploadUrl string, endpoint string, expiry time.Duration, root string, report junit.Report) (junit.Report, error) {
+	target, err := parseStorageTarget(uploadUrl, endpoint)
+	if err != nil {
+		return report, err
+	}
+
+	urls := map[string]string{}
+	upload := func(attachment junit.Attachment) string {
+		if objectUrl, ok := urls[attachment.Path]; ok {
+			return objectUrl
+		}
+		urls[attachment.Path] = ""
+
+		file := filepath.FromSlash(attachment.Path)
+		if _, err := os.Stat(file); os.IsNotExist(err) && !filepath.IsAbs(file) {
+			file = filepath.Join(root, file)
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			logger.Warn("could not read attachment", "path", attachment.Path, "error", err)
+			return ""
+		}
+		if info.Size() > maxAttachmentSize {
+			logger.Warn("attachment too large to upload", "path", attachment.Path, "size", info.Size())
+			return ""
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.Warn("could not read attachment", "path", attachment.Path, "error", err)
+			return ""
+		}
+
+		// attachments are named by their content, so that those of different
+		// testcases with the same name do not overwrite each other
+		contentType := mime.TypeByExtension(filepath.Ext(file))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		objectUrl, err := uploadObject(ctx, target, "attachments/"+sha256Hex(data)[:16]+"/"+filepath.Base(file), contentType, data, expiry)
+		if err != nil {
+			logger.Warn("could not upload attachment", "path", attachment.Path, "error", err)
+			return ""
+		}
+		urls[attachment.Path] = objectUrl
+		return objectUrl
+	}
+
+	uploaded := report
+	uploaded.Suites = make([]junit.Suite, len(report.Suites))
+	for i, suite := range report.Suites {
+		cases := make([]junit.Case, len(suite.Cases))
+		for j, testcase := range suite.Cases {
+			if testcase.Failed() && len(testcase.Attachments) > 0 {
+				attachments := make([]junit.Attachment, len(testcase.Attachments))
+				for k, attachment := range testcase.Attachments {
+					attachment.Url = upload(attachment)
+					attachments[k] = attachment
+				}
+				testcase.Attachments = attachments
+			}
+			cases[j] = testcase
+		}
+		suite.Cases = cases
+		uploaded.Suites[i] = suite
+	}
+	logger.Debug("uploaded attachments", "count", len(urls))
+	return uploaded, nil
+}
+
+func (t storageTarget) objectUrl(name string) string {
+	key := name
+	if t.Prefix != "" {
+		key = t.Prefix + "/" + name
+	}
+	return t.Endpoint + "/" + uriEncode(t.Bucket, true) + "/" + uriEncode(key, false)
+}
+
+// uploadObject puts the data at prefix/name, returning a url the object can be
+// fetched from. The url is presigned when expiry is greater than zero.
+func uploadObject(ctx context.Context, target storageTarget, name string, contentType string, data []byte, expiry time.Duration) (string, error) {
+	objectUrl := target.objectUrl(name)
+	now := time.Now().UTC()
+	payloadHash := sha256Hex(data)
+
+	headers := map[string]string{
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	if target.Token != "" {
+		headers["x-amz-security-token"] = target.Token
+	}
+
+	u, err := url.Parse(objectUrl)
+	if err != nil {
+		return "", err
+	}
+
+	headers["Authorization"] = target.authorization("PUT", u, headers, payloadHash, now)
+	headers["Content-Type"] = contentType
+
+	if _, err := sendRequest(ctx, "PUT", objectUrl, headers, data, 200); err != nil {
+		return "", err
+	}
+
+	if expiry <= 0 {
+		return objectUrl, nil
+	}
+
+	return target.presign(u, expiry, now), nil
+}
+
+func (t storageTarget) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), t.Region)
+}
+
+func (t storageTarget) signature(stringToSign string, now time.Time) string {
+	key := hmacSHA256([]byte("AWS4"+t.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, t.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (t storageTarget) authorization(method string, u *url.URL, headers map[string]string, payloadHash string, now time.Time) string {
+	canonicalHeaders := map[string]string{"host": u.Host}
+	for key, value := range headers {
+		canonicalHeaders[strings.ToLower(key)] = strings.TrimSpace(value)
+	}
+
+	var names []string
+	for name := range canonicalHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, name+":"+canonicalHeaders[name]+"\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{method, u.EscapedPath(), "", strings.Join(lines, ""), signedHeaders, payloadHash}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), t.scope(now), sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", t.AccessKey, t.scope(now), signedHeaders, t.signature(stringToSign, now))
+}
+
+func (t storageTarget) presign(u *url.URL, expiry time.Duration, now time.Time) string {
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    t.AccessKey + "/" + t.scope(now),
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       fmt.Sprintf("%d", int(expiry.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if t.Token != "" {
+		query["X-Amz-Security-Token"] = t.Token
+	}
+
+	var names []string
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []string
+	for _, name := range names {
+		params = append(params, uriEncode(name, true)+"="+uriEncode(query[name], true))
+	}
+	canonicalQuery := strings.Join(params, "&")
+
+	canonicalRequest := strings.Join([]string{"GET", u.EscapedPath(), canonicalQuery, "host:" + u.Host + "\n", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), t.scope(now), sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", u.Scheme, u.Host, u.EscapedPath(), canonicalQuery, t.signature(stringToSign, now))
+}
+
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+var teamcityEscaper = strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]")
+
+func writeTeamcityMessages(w io.Writer, testsuites []junit.Suite) {
+	for _, testsuite := range testsuites {
+		suite := teamcityEscaper.Replace(testsuite.Name)
+		fmt.Fprintf(w, "##teamcity[testSuiteStarted name='%s']\n", suite)
+		for _, testcase := range testsuite.Cases {
+			name := teamcityEscaper.Replace(testcase.Id())
+
+			fmt.Fprintf(w, "##teamcity[testStarted name='%s']\n", name)
+			switch {
+			case testcase.Failed():
+				fmt.Fprintf(w, "##teamcity[testFailed name='%s' message='%s' details='%s']\n", name, teamcityEscaper.Replace(teamcityFailureMessage(testcase.Failure)), teamcityEscaper.Replace(strings.TrimSpace(testcase.Failure.Message)))
+			case testcase.Status == junit.StatusSkipped:
+				fmt.Fprintf(w, "##teamcity[testIgnored name='%s' message='%s']\n", name, teamcityEscaper.Replace(testcase.SkipMessage))
+			}
+			fmt.Fprintf(w, "##teamcity[testFinished name='%s' duration='%d']\n", name, testcase.Duration().Milliseconds())
+		}
+		fmt.Fprintf(w, "##teamcity[testSuiteFinished name='%s']\n", suite)
+	}
+}
+
+// teamcityFailureMessage is the first line of the failure message, or its
+// type when it has no message
+func teamcityFailureMessage(failure junit.Failure) string {
+	for _, line := range strings.Split(failure.Message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return failure.Type
+}
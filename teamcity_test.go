@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+func TestWriteTeamcityMessages(t *testing.T) {
+	suites := []junit.Suite{{Name: "api", Cases: []junit.Case{
+		{Classname: "api", Name: "login", Time: 0.25, Status: junit.StatusPassed},
+		{Classname: "api", Name: "logout", Time: 1, Status: junit.StatusFailed, Failure: junit.Failure{Type: "AssertionError", Message: "\nexpected 200 [got 500]\n  at api_test.go:12\n"}},
+		{Classname: "api", Name: "panic", Status: junit.StatusError, Failure: junit.Failure{Type: "RuntimeError"}},
+		{Classname: "api", Name: "signup", Status: junit.StatusSkipped, SkipMessage: "not 'ready'"},
+	}}}
+
+	var output strings.Builder
+	writeTeamcityMessages(&output, suites)
+
+	want := `##teamcity[testSuiteStarted name='api']
+##teamcity[testStarted name='api.login']
+##teamcity[testFinished name='api.login' duration='250']
+##teamcity[testStarted name='api.logout']
+##teamcity[testFailed name='api.logout' message='expected 200 |[got 500|]' details='expected 200 |[got 500|]|n  at api_test.go:12']
+##teamcity[testFinished name='api.logout' duration='1000']
+##teamcity[testStarted name='api.panic']
+##teamcity[testFailed name='api.panic' message='RuntimeError' details='']
+##teamcity[testFinished name='api.panic' duration='0']
+##teamcity[testStarted name='api.signup']
+##teamcity[testIgnored name='api.signup' message='not |'ready|'']
+##teamcity[testFinished name='api.signup' duration='0']
+##teamcity[testSuiteFinished name='api']
+`
+	if output.String() != want {
+		t.Errorf("writeTeamcityMessages() =\n%s\nwant\n%s", output.String(), want)
+	}
+}
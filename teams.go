@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+const teamsMaxFailures = 10
+
+func postTeamsMessage(ctx context.Context, webhookUrl string, title string, summary junit.Summary, testsuites []junit.Suite, commentUrl string, jobUrl string) error {
+	if title == "" {
+		title = "Test results"
+	}
+
+	status := "Passed"
+	color := "Good"
+	if summary.Failed() {
+		status = "Failed"
+		color = "Attention"
+	}
+
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": title, "size": "Large", "weight": "Bolder", "wrap": true},
+		{"type": "TextBlock", "text": status, "color": color, "weight": "Bolder"},
+		{
+			"type": "FactSet",
+			"facts": []map[string]string{
+				{"title": "Tests", "value": fmt.Sprintf("%d", summary.Tests)},
+				{"title": "Failures", "value": fmt.Sprintf("%d", summary.Failures)},
+				{"title": "Errors", "value": fmt.Sprintf("%d", summary.Errors)},
+				{"title": "Skipped", "value": fmt.Sprintf("%d", summary.Skipped)},
+				{"title": "Pass rate", "value": fmt.Sprintf("%.1f%%", summary.PassRate())},
+			},
+		},
+	}
+
+	var failures []map[string]interface{}
+	count := 0
+	for _, testsuite := range testsuites {
+		for _, testcase := range testsuite.Cases {
+			if !testcase.Failed() {
+				continue
+			}
+
+			count++
+			if count > teamsMaxFailures {
+				continue
+			}
+
+			failures = append(failures,
+				map[string]interface{}{"type": "TextBlock", "text": fmt.Sprintf("%s: %s", testsuite.Name, testcase.Name), "weight": "Bolder", "wrap": true},
+				map[string]interface{}{"type": "TextBlock", "text": strings.TrimSpace(testcase.Failure.Message), "fontType": "Monospace", "size": "Small", "wrap": true, "maxLines": 10},
+			)
+		}
+	}
+	if count > teamsMaxFailures {
+		failures = append(failures, map[string]interface{}{"type": "TextBlock", "text": fmt.Sprintf("and %d more failures", count-teamsMaxFailures), "isSubtle": true})
+	}
+
+	var actions []map[string]interface{}
+	if len(failures) > 0 {
+		body = append(body, map[string]interface{}{"type": "Container", "id": "failures", "isVisible": false, "items": failures})
+		actions = append(actions, map[string]interface{}{"type": "Action.ToggleVisibility", "title": "Show failures", "targetElements": []string{"failures"}})
+	}
+	if commentUrl != "" {
+		actions = append(actions, map[string]interface{}{"type": "Action.OpenUrl", "title": "View comment", "url": commentUrl})
+	}
+	if jobUrl != "" {
+		actions = append(actions, map[string]interface{}{"type": "Action.OpenUrl", "title": "View job", "url": jobUrl})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if len(actions) > 0 {
+		card["actions"] = actions
+	}
+
+	message := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+
+	_, err := sendJSON(ctx, "POST", webhookUrl, nil, message, 0)
+	return err
+}
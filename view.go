@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+type viewOptions struct {
+	Filter  *filterOptions
+	Parsing *parsingOptions
+}
+
+func newViewFlags() (*flag.FlagSet, *viewOptions) {
+	flags := flag.NewFlagSet("xunit-to-github view", flag.ExitOnError)
+	options := &viewOptions{}
+	options.Filter = addFilterFlags(flags)
+	options.Parsing = addParsingFlags(flags)
+	addCommonFlags(flags)
+	return flags, options
+}
+
+// runView browses xml reports in an interactive terminal ui
+func runView(args []string) {
+	flags, options := newViewFlags()
+	parseFlags(flags, args, false)
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+
+	filter, err := options.Filter.compile()
+	if err != nil {
+		logger.Fatal(exitConfigError, "invalid filter", "error", err)
+	}
+
+	files, missing, err := options.Parsing.findFiles(flags.Args())
+	if err != nil {
+		logger.Fatal(exitParseError, "could not find reports", "error", err)
+	}
+
+	results, err := options.Parsing.parseFiles(ctx, files)
+	if err != nil {
+		logger.Fatal(exitParseError, "could not parse reports", "error", err)
+	}
+	results.MissingPaths = missing
+
+	state, err := stty("-g")
+	if err != nil {
+		logger.Fatal(exitConfigError, "could not open terminal", "error", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		logger.Fatal(exitConfigError, "could not open terminal", "error", err)
+	}
+
+	// switch to the alternate screen and hide the cursor until exiting
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	v := &viewer{results: junit.FilterReport(junit.MarkFlaky(results), filter), suite: -1, testcase: -1}
+	err = v.run(os.Stdin, os.Stdout)
+	fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+	stty(strings.TrimSpace(state))
+
+	if err != nil {
+		logger.Fatal(exitConfigError, "could not read input", "error", err)
+	}
+}
+
+// stty runs stty against the terminal on stdin
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// terminalSize returns the rows and columns of the terminal, falling back to
+// 24x80 when they cannot be determined
+func terminalSize() (int, int) {
+	var rows, columns int
+	if output, err := stty("size"); err == nil {
+		fmt.Sscan(output, &rows, &columns)
+	}
+	if rows <= 4 || columns <= 0 {
+		return 24, 80
+	}
+	return rows, columns
+}
+
+type viewEntry struct {
+	index  int
+	label  string
+	failed bool
+}
+
+// viewer holds the state of the terminal ui, which lists suites, the
+// testcases of the selected suite, or the details of the selected testcase
+type viewer struct {
+	results    junit.Report
+	suite      int
+	testcase   int
+	cursor     int
+	query      string
+	searching  bool
+	failedOnly bool
+}
+
+func (v *viewer) entries() []viewEntry {
+	var entries []viewEntry
+	if v.suite == -1 {
+		for i, testsuite := range v.results.Suites {
+			entries = append(entries, viewEntry{i, fmt.Sprintf("%s (%s)", testsuite.Name, testsuite.Summary.String()), testsuite.Failed()})
+		}
+	} else {
+		for i, testcase := range v.results.Suites[v.suite].Cases {
+			entries = append(entries, viewEntry{i, fmt.Sprintf("%s in %gsec", testcase.Id(), testcase.Time), testcase.Failed()})
+		}
+	}
+
+	var matching []viewEntry
+	query := strings.ToLower(v.query)
+	for _, entry := range entries {
+		if v.failedOnly && !entry.failed {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(entry.label), query) {
+			continue
+		}
+		matching = append(matching, entry)
+	}
+	return matching
+}
+
+func (v *viewer) details() []string {
+	testcase := v.results.Suites[v.suite].Cases[v.testcase]
+	lines := []string{
+		"Suite:     " + v.results.Suites[v.suite].Name,
+		"Classname: " + testcase.Classname,
+		"Name:      " + testcase.Name,
+		fmt.Sprintf("Time:      %gsec", testcase.Time),
+		"Status:    " + string(testcase.Status),
+	}
+	if testcase.SkipMessage != "" {
+		lines = append(lines, "Reason:    "+testcase.SkipMessage)
+	}
+	if testcase.Failed() {
+		if testcase.Failure.Type != "" {
+			lines = append(lines, "Type:      "+testcase.Failure.Type)
+		}
+		if testcase.Location != nil {
+			lines = append(lines, "Location:  "+testcase.Location.String())
+		}
+		lines = append(lines, "")
+		lines = append(lines, strings.Split(strings.TrimSpace(testcase.Failure.Message), "\n")...)
+	}
+	return lines
+}
+
+func (v *viewer) draw(w io.Writer) {
+	rows, columns := terminalSize()
+	lines := []string{}
+
+	header := "Suites: " + v.results.Summary.String()
+	if v.suite != -1 {
+		header = "Suite: " + v.results.Suites[v.suite].Name
+	}
+	lines = append(lines, "\x1b[1m"+truncate(header, columns)+"\x1b[0m", "")
+
+	height := rows - 4
+	if v.testcase != -1 {
+		details := v.details()
+		if v.cursor > len(details)-1 {
+			v.cursor = len(details) - 1
+		}
+		end := v.cursor + height
+		if end > len(details) {
+			end = len(details)
+		}
+		for _, line := range details[v.cursor:end] {
+			lines = append(lines, truncate(line, columns))
+		}
+	} else {
+		entries := v.entries()
+		if v.cursor >= len(entries) {
+			v.cursor = len(entries) - 1
+		}
+		if v.cursor < 0 {
+			v.cursor = 0
+		}
+
+		offset := 0
+		if v.cursor >= height {
+			offset = v.cursor - height + 1
+		}
+		for i := offset; i < len(entries) && i < offset+height; i++ {
+			mark := "\x1b[32mok\x1b[0m     "
+			if entries[i].failed {
+				mark = "\x1b[31mnot ok\x1b[0m "
+			}
+			line := mark + truncate(entries[i].label, columns-7)
+			if i == v.cursor {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+			lines = append(lines, line)
+		}
+		if len(entries) == 0 {
+			lines = append(lines, "no matches")
+		}
+	}
+
+	for len(lines) < rows-1 {
+		lines = append(lines, "")
+	}
+
+	footer := "j/k move  enter open  h back  / search  f failures only  q quit"
+	if v.searching {
+		footer = "/" + v.query
+	} else if v.query != "" {
+		footer = "search: " + v.query + "  (esc to clear)  " + footer
+	}
+	lines = append(lines, "\x1b[2m"+truncate(footer, columns)+"\x1b[0m")
+
+	fmt.Fprint(w, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+}
+
+func (v *viewer) open() {
+	if v.testcase != -1 {
+		return
+	}
+
+	entries := v.entries()
+	if len(entries) == 0 {
+		return
+	}
+	if v.suite == -1 {
+		v.suite = entries[v.cursor].index
+	} else {
+		v.testcase = entries[v.cursor].index
+	}
+	v.cursor = 0
+	v.query = ""
+}
+
+func (v *viewer) back() {
+	index := v.suite
+	if v.testcase != -1 {
+		index = v.testcase
+		v.testcase = -1
+	} else if v.suite != -1 {
+		v.suite = -1
+	} else {
+		return
+	}
+
+	// return the cursor to the entry that was opened
+	v.query = ""
+	v.cursor = 0
+	for i, entry := range v.entries() {
+		if entry.index == index {
+			v.cursor = i
+		}
+	}
+}
+
+// run draws the ui and handles keypresses until the user quits
+func (v *viewer) run(r io.Reader, w io.Writer) error {
+	buffer := make([]byte, 16)
+	for {
+		v.draw(w)
+		n, err := r.Read(buffer)
+		if err != nil {
+			return err
+		}
+
+		key := string(buffer[:n])
+		if v.searching {
+			switch key {
+			case "\r", "\n":
+				v.searching = false
+			case "\x1b":
+				v.searching = false
+				v.query = ""
+			case "\x7f", "\b":
+				if len(v.query) > 0 {
+					v.query = v.query[:len(v.query)-1]
+				}
+			default:
+				if key[0] >= ' ' {
+					v.query += key
+				}
+			}
+			v.cursor = 0
+			continue
+		}
+
+		switch key {
+		case "q", "\x03":
+			return nil
+		case "k", "\x1b[A":
+			if v.cursor > 0 {
+				v.cursor--
+			}
+		case "j", "\x1b[B":
+			v.cursor++
+		case "\r", "\n", "l", "\x1b[C":
+			v.open()
+		case "h", "\x7f", "\b", "\x1b[D":
+			v.back()
+		case "\x1b":
+			v.query = ""
+		case "/":
+			if v.testcase == -1 {
+				v.searching = true
+				v.query = ""
+			}
+		case "f":
+			v.failedOnly = !v.failedOnly
+			v.cursor = 0
+		}
+	}
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
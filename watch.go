@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+// watchReports checks the paths for new or modified reports every interval,
+// calling render with every report found whenever they change, until the
+// process is interrupted. Paths that do not exist yet are left out of each
+// check when IgnoreMissing is set, as are reports modified before Since.
+func watchReports(paths []string, parsing *parsingOptions, interval time.Duration, render func(files []string)) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := ""
+	for {
+		existing := paths
+		if parsing.IgnoreMissing {
+			existing, _ = existingPaths(paths)
+		}
+
+		var files []string
+		var err error
+		if len(existing) > 0 || len(paths) == 0 {
+			files, err = junit.GetFiles(existing)
+			files = parsing.modifiedSince(files)
+		}
+		if err != nil {
+			logger.Debug("could not find reports", "error", err)
+		} else if current := fingerprintFiles(files); current != previous {
+			previous = current
+			render(files)
+		}
+
+		select {
+		case <-interrupt:
+			logger.Debug("stopped watching reports")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fingerprintFiles describes the name, size, and modification time of each
+// file, so that changes to any of them can be detected
+func fingerprintFiles(files []string) string {
+	var fingerprint strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String()
+}
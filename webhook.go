@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/josegonzalez/go-xunit-to-github/pkg/junit"
+)
+
+const webhookSignatureHeader = "X-Xunit-To-Github-Signature"
+
+func postWebhook(ctx context.Context, webhookUrl string, rawHeaders []string, secret string, summary junit.Summary, testsuites []junit.Suite, body string) error {
+	headers := map[string]string{}
+	for _, header := range rawHeaders {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid webhook header, expected 'Name: value': %s", header)
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	data, err := json.Marshal(junit.Report{Summary: summary, Suites: testsuites, Body: body})
+	if err != nil {
+		return err
+	}
+
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		headers[webhookSignatureHeader] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	_, err = sendRequest(ctx, "POST", webhookUrl, headers, data, 0)
+	return err
+}